@@ -1,17 +1,23 @@
 package djson
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"math"
 	"reflect"
 	"sort"
+	"time"
 
 	"github.com/volatiletech/null/v8"
 )
 
+// DA is a JSON array. Element is empty until a lazily parsed array is
+// loaded by a method; see ParseLazy.
+
 type DA struct {
 	SeekPointer int
 	Element     []interface{}
+	lazy        *lazyRaw // not yet decoded, see ParseLazy
 }
 
 func NewArray() *DA {
@@ -109,6 +115,10 @@ func (m *DA) ReplaceAt(idx int, value interface{}) *DA {
 		m.Element[idx] = ConverMapToObject(t)
 	case Array:
 		m.Element[idx] = ConvertSliceToArray(t)
+	case time.Time:
+		m.Element[idx] = t.Format(time.RFC3339Nano)
+	case []byte:
+		m.Element[idx] = base64.StdEncoding.EncodeToString(t)
 	case DJSON:
 		m.Element[idx] = t.GetAsInterface()
 	case *DJSON:
@@ -143,6 +153,7 @@ func (m *DA) Put(value interface{}) *DA {
 
 	switch t := value.(type) {
 	case *DA:
+		t.load()
 		for idx := range t.Element {
 			m.Insert(m.Size(), t.Element[idx])
 		}
@@ -274,10 +285,12 @@ func (m *DA) Put(value interface{}) *DA {
 }
 
 func (m *DA) Size() int {
+	m.load()
 	return len(m.Element)
 }
 
 func (m *DA) Length() int {
+	m.load()
 	return len(m.Element)
 }
 
@@ -368,6 +381,7 @@ func (m *DA) GetAsObject(idx int) (*DO, bool) {
 	case DO:
 		return &t, true
 	case *DO:
+		t.load()
 		return t, true
 	}
 
@@ -383,6 +397,7 @@ func (m *DA) GetAsArray(idx int) (*DA, bool) {
 	case DA:
 		return &t, true
 	case *DA:
+		t.load()
 		return t, true
 	}
 
@@ -436,17 +451,15 @@ func (m *DA) GetAsString2(idx int) (string, bool) {
 }
 
 func (m *DA) ToStringPretty() string {
-	jsonByte, _ := json.MarshalIndent(ConvertArrayToSlice(m), "", "   ")
-	return string(jsonByte)
+	return marshalString(m, toStringPrettyOptions)
 }
 
 func (m *DA) ToString() string {
-	jsonByte, _ := json.Marshal(ConvertArrayToSlice(m))
-	return string(jsonByte)
+	return marshalString(m, toStringOptions)
 }
 
 func (m *DA) SortObject(isAsc bool, key string) bool {
-	numElement := len(m.Element)
+	numElement := m.Size()
 
 	if numElement == 0 {
 		return false
@@ -577,7 +590,7 @@ func (m *DA) SortObject(isAsc bool, key string) bool {
 
 func (m *DA) Sort(isAsc bool) bool {
 
-	numElement := len(m.Element)
+	numElement := m.Size()
 
 	if numElement == 0 {
 		return false
@@ -740,6 +753,9 @@ func (m *DA) Equal(t *DA) bool {
 }
 
 func (m *DA) Clone() *DA {
+	if m.lazy != nil { // raw bytes are never modified
+		return &DA{lazy: m.lazy}
+	}
 
 	t := NewArray()
 
@@ -748,6 +764,7 @@ func (m *DA) Clone() *DA {
 	for i := range m.Element {
 		if m.Element[i] == nil {
 			t.Element[i] = nil
+			continue
 		}
 
 		mtype := reflect.TypeOf(m.Element[i]).String()
@@ -779,13 +796,13 @@ func (m *DA) Clone() *DA {
 func (m *DA) Seek(seekp ...int) {
 	m.SeekPointer = 0
 
-	if len(seekp) > 0 && len(m.Element) > seekp[0] {
+	if len(seekp) > 0 && m.Size() > seekp[0] {
 		m.SeekPointer = seekp[0]
 	}
 }
 
 func (m *DA) Next() (interface{}, bool) {
-	if len(m.Element) <= m.SeekPointer {
+	if m.Size() <= m.SeekPointer {
 		return nil, false
 	}
 
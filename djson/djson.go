@@ -1,9 +1,11 @@
 package djson
 
 import (
+	"encoding/base64"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	gov "github.com/asaskevich/govalidator"
 )
@@ -26,6 +28,8 @@ type DJSON struct {
 	Float    float64
 	Bool     bool
 	JsonType int
+	frozen   bool
+	watchers *watcherSet
 }
 
 func NewDJSON(v ...int) *DJSON {
@@ -121,6 +125,10 @@ func NewArrayJSON(v ...interface{}) *DJSON {
 }
 
 func (m *DJSON) SetAsObject() *DJSON {
+	if m.frozen {
+		return m
+	}
+
 	m.Object = NewObject()
 	m.Array = nil
 	m.JsonType = JSON_OBJECT
@@ -129,6 +137,10 @@ func (m *DJSON) SetAsObject() *DJSON {
 }
 
 func (m *DJSON) SetAsArray() *DJSON {
+	if m.frozen {
+		return m
+	}
+
 	m.Array = NewArray()
 	m.Object = nil
 	m.JsonType = JSON_ARRAY
@@ -138,7 +150,7 @@ func (m *DJSON) SetAsArray() *DJSON {
 
 func (m *DJSON) Parse(doc string) *DJSON {
 
-	if m.JsonType != JSON_NULL {
+	if m.JsonType != JSON_NULL || m.frozen {
 		return m
 	}
 
@@ -186,10 +198,19 @@ func (m *DJSON) Parse(doc string) *DJSON {
 
 func (m *DJSON) Put(v ...interface{}) *DJSON {
 
-	if IsEmptyArg(v) {
+	if IsEmptyArg(v) || m.frozen {
 		return m
 	}
 
+	if m.watchers != nil {
+		return m.putWatched(v...)
+	}
+
+	return m.put(v...)
+}
+
+func (m *DJSON) put(v ...interface{}) *DJSON {
+
 	if len(v) == 2 {
 
 		if key, ok := v[0].(string); ok {
@@ -213,6 +234,13 @@ func (m *DJSON) Put(v ...interface{}) *DJSON {
 
 	// length of v must be 1
 
+	switch t := v[0].(type) {
+	case time.Time:
+		return m.put(t.Format(time.RFC3339Nano))
+	case []byte:
+		return m.put(base64.StdEncoding.EncodeToString(t))
+	}
+
 	if v[0] == nil {
 		m.Array = nil
 		m.Object = nil
@@ -291,6 +319,7 @@ func (m *DJSON) Put(v ...interface{}) *DJSON {
 		}
 	case *DO:
 		if m.JsonType == JSON_OBJECT {
+			t.load()
 			for key := range t.Map {
 				m.Object.Put(key, t.Map[key])
 			}
@@ -301,6 +330,7 @@ func (m *DJSON) Put(v ...interface{}) *DJSON {
 		}
 	case DO:
 		if m.JsonType == JSON_OBJECT {
+			t.load()
 			for key := range t.Map {
 				m.Object.Put(key, t.Map[key])
 			}
@@ -354,6 +384,10 @@ func (m *DJSON) Put(v ...interface{}) *DJSON {
 }
 
 func (m *DJSON) PutAsArray(value ...interface{}) *DJSON {
+	if m.frozen {
+		return m
+	}
+
 	if m.JsonType == JSON_NULL {
 		m.Array = NewArray()
 		m.JsonType = JSON_ARRAY
@@ -367,6 +401,10 @@ func (m *DJSON) PutAsArray(value ...interface{}) *DJSON {
 }
 
 func (m *DJSON) PutAsObject(key string, value interface{}) *DJSON {
+	if m.frozen {
+		return m
+	}
+
 	if m.JsonType == JSON_NULL {
 		m.Object = NewObject()
 		m.JsonType = JSON_OBJECT
@@ -380,6 +418,12 @@ func (m *DJSON) PutAsObject(key string, value interface{}) *DJSON {
 }
 
 func (m *DJSON) Remove(key interface{}) *DJSON {
+	if m.frozen {
+		return m
+	}
+
+	done := m.trackRemoval(key)
+
 	switch tkey := key.(type) {
 	case string:
 		if m.JsonType == JSON_OBJECT {
@@ -391,6 +435,8 @@ func (m *DJSON) Remove(key interface{}) *DJSON {
 		}
 	}
 
+	done(true)
+
 	return m
 }
 
@@ -441,6 +487,7 @@ func (m *DJSON) Get(key ...interface{}) (*DJSON, bool) {
 	} else {
 
 		r := NewDJSON()
+		r.frozen = m.frozen
 		var element interface{}
 		var retOk bool
 
@@ -489,9 +536,11 @@ func (m *DJSON) Get(key ...interface{}) (*DJSON, bool) {
 			r.Object = &t
 			r.JsonType = JSON_OBJECT
 		case *DA:
+			t.load()
 			r.Array = t
 			r.JsonType = JSON_ARRAY
 		case *DO:
+			t.load()
 			r.Object = t
 			r.JsonType = JSON_OBJECT
 		default:
@@ -539,6 +588,7 @@ func (m *DJSON) GetAsObject(key ...interface{}) (*DJSON, bool) {
 				Object:   newObject,
 				Array:    nil,
 				JsonType: JSON_OBJECT,
+				frozen:   m.frozen,
 			}, true
 		}
 	}
@@ -583,6 +633,7 @@ func (m *DJSON) GetAsArray(key ...interface{}) (*DJSON, bool) {
 				Object:   nil,
 				Array:    newArray,
 				JsonType: JSON_ARRAY,
+				frozen:   m.frozen,
 			}, true
 		}
 
@@ -840,6 +891,13 @@ func (m *DJSON) ToString() string {
 }
 
 func (m *DJSON) ReplaceAt(k interface{}, v interface{}) *DJSON {
+	if m.frozen {
+		return m
+	}
+
+	done := m.trackChange(k)
+	defer done(true)
+
 	switch tkey := k.(type) {
 	case string:
 		if m.JsonType == JSON_OBJECT {
@@ -908,6 +966,10 @@ func (m *DJSON) Next() *DJSON {
 			ret.JsonType = JSON_NULL
 		}
 
+		if m.frozen {
+			ret.frozen = true
+		}
+
 		return ret
 	}
 
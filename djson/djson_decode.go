@@ -0,0 +1,489 @@
+package djson
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// same nesting limit as encoding/json
+const maxNestingDepth = 10000
+
+// decoder scans JSON text and builds *DO / *DA directly without the
+// map[string]interface{} round trip of encoding/json.
+
+type decoder struct {
+	data    []byte
+	pos     int
+	opts    *ParseOptions
+	errPath []interface{} // filled while unwinding from a LimitError
+	scratch []byte
+	lazy    bool // keep nested objects and arrays as raw bytes
+	altered bool // skip met a duplicate key or a non-finite number
+}
+
+// decodeDocument decodes data, which must be a single JSON value.
+
+func decodeDocument(data []byte, opts *ParseOptions) (interface{}, error) {
+	return decode(data, opts, false)
+}
+
+// decodeFirst decodes the first JSON value in data and ignores what follows,
+// as a json.Decoder does.
+
+func decodeFirst(data []byte, opts *ParseOptions) (interface{}, error) {
+	return decode(data, opts, true)
+}
+
+func decode(data []byte, opts *ParseOptions, trailing bool) (interface{}, error) {
+	d := &decoder{
+		data: data,
+		opts: opts,
+		lazy: opts.Lazy && opts.DuplicateKey != DUPLICATE_KEY_ERROR,
+	}
+
+	d.skipSpace()
+
+	v, err := d.value(0)
+	if err == nil && !trailing {
+		d.skipSpace()
+		if d.pos < len(d.data) {
+			err = d.syntaxError("after top-level value")
+		}
+	}
+
+	if err != nil {
+		if lerr, ok := err.(*LimitError); ok && d.errPath != nil {
+			token := make([]interface{}, len(d.errPath))
+			for idx := range d.errPath {
+				token[len(d.errPath)-1-idx] = d.errPath[idx]
+			}
+			lerr.Path = BuildPath(token...)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (d *decoder) syntaxError(what string) error {
+	if d.pos >= len(d.data) {
+		return fmt.Errorf("unexpected end of JSON input")
+	}
+	return fmt.Errorf("invalid character %q %s at offset %d", d.data[d.pos], what, d.pos)
+}
+
+func (d *decoder) limitError(limit string, max int) error {
+	d.errPath = make([]interface{}, 0)
+	return &LimitError{Limit: limit, Max: max}
+}
+
+func (d *decoder) unwind(err error, token interface{}) error {
+	if d.errPath != nil {
+		d.errPath = append(d.errPath, token)
+	}
+	return err
+}
+
+func (d *decoder) skipSpace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, d.syntaxError("")
+	}
+
+	switch c := d.data[d.pos]; c {
+	case '{':
+		if err := d.checkDepth(depth + 1); err != nil {
+			return nil, err
+		}
+		return d.object(depth + 1)
+	case '[':
+		if err := d.checkDepth(depth + 1); err != nil {
+			return nil, err
+		}
+		return d.array(depth + 1)
+	case '"':
+		return d.string()
+	case 't':
+		return true, d.literal("true")
+	case 'f':
+		return false, d.literal("false")
+	case 'n':
+		return nil, d.literal("null")
+	default:
+		if c == '-' || (c >= '0' && c <= '9') {
+			return d.number()
+		}
+	}
+
+	return nil, d.syntaxError("looking for beginning of value")
+}
+
+func (d *decoder) checkDepth(depth int) error {
+	if d.opts.MaxDepth > 0 && depth > d.opts.MaxDepth {
+		return d.limitError(LIMIT_DEPTH, d.opts.MaxDepth)
+	}
+
+	if depth > maxNestingDepth {
+		return d.limitError(LIMIT_DEPTH, maxNestingDepth)
+	}
+
+	return nil
+}
+
+func (d *decoder) literal(lit string) error {
+	if len(d.data)-d.pos < len(lit) || string(d.data[d.pos:d.pos+len(lit)]) != lit {
+		return d.syntaxError("in literal")
+	}
+
+	d.pos += len(lit)
+	return nil
+}
+
+func (d *decoder) object(depth int) (*DO, error) {
+	obj := NewObject()
+
+	d.pos++ // '{'
+	d.skipSpace()
+
+	if d.pos < len(d.data) && d.data[d.pos] == '}' {
+		d.pos++
+		return obj, nil
+	}
+
+	for {
+		if d.pos >= len(d.data) || d.data[d.pos] != '"' {
+			return nil, d.syntaxError("looking for beginning of object key string")
+		}
+
+		key, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+
+		_, exists := obj.Map[key]
+
+		if exists && d.opts.DuplicateKey == DUPLICATE_KEY_ERROR {
+			d.errPath = make([]interface{}, 0)
+			return nil, d.unwind(&LimitError{Limit: LIMIT_DUPLICATE_KEY}, key)
+		}
+
+		if !exists && d.opts.MaxObjectKeys > 0 && len(obj.Map) >= d.opts.MaxObjectKeys {
+			return nil, d.limitError(LIMIT_OBJECT_KEYS, d.opts.MaxObjectKeys)
+		}
+
+		d.skipSpace()
+		if d.pos >= len(d.data) || d.data[d.pos] != ':' {
+			return nil, d.syntaxError("after object key")
+		}
+		d.pos++
+		d.skipSpace()
+
+		v, err := d.child(depth)
+		if err != nil {
+			return nil, d.unwind(err, key)
+		}
+
+		// non-finite numbers are dropped like DO.Put does
+		if (!exists || d.opts.DuplicateKey != DUPLICATE_KEY_FIRST_WINS) && isFiniteValue(v) {
+			if !exists {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.Map[key] = v
+		}
+
+		d.skipSpace()
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError("")
+		}
+
+		if d.data[d.pos] == ',' {
+			d.pos++
+			d.skipSpace()
+			continue
+		}
+
+		if d.data[d.pos] == '}' {
+			d.pos++
+			return obj, nil
+		}
+
+		return nil, d.syntaxError("after object key:value pair")
+	}
+}
+
+func (d *decoder) array(depth int) (*DA, error) {
+	arr := NewArray()
+
+	d.pos++ // '['
+	d.skipSpace()
+
+	if d.pos < len(d.data) && d.data[d.pos] == ']' {
+		d.pos++
+		return arr, nil
+	}
+
+	for {
+		if d.opts.MaxArrayLength > 0 && len(arr.Element) >= d.opts.MaxArrayLength {
+			return nil, d.limitError(LIMIT_ARRAY, d.opts.MaxArrayLength)
+		}
+
+		v, err := d.child(depth)
+		if err != nil {
+			return nil, d.unwind(err, len(arr.Element))
+		}
+
+		if !isFiniteValue(v) {
+			v = nil // DA.ReplaceAt keeps nil for NaN and Inf
+		}
+
+		arr.Element = append(arr.Element, v)
+
+		d.skipSpace()
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError("")
+		}
+
+		if d.data[d.pos] == ',' {
+			d.pos++
+			d.skipSpace()
+			continue
+		}
+
+		if d.data[d.pos] == ']' {
+			d.pos++
+			return arr, nil
+		}
+
+		return nil, d.syntaxError("after array element")
+	}
+}
+
+func (d *decoder) number() (interface{}, error) {
+	start := d.pos
+
+	isFloat, err := d.scanNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(d.data[start:d.pos])
+
+	if !isFloat {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+	}
+
+	f, _ := strconv.ParseFloat(s, 64)
+	return f, nil
+}
+
+func (d *decoder) scanNumber() (bool, error) {
+	isFloat := false
+
+	if d.data[d.pos] == '-' {
+		d.pos++
+	}
+
+	if d.pos >= len(d.data) {
+		return false, d.syntaxError("in numeric literal")
+	}
+
+	if d.data[d.pos] == '0' {
+		d.pos++
+	} else if d.data[d.pos] >= '1' && d.data[d.pos] <= '9' {
+		d.skipDigits()
+	} else {
+		return false, d.syntaxError("in numeric literal")
+	}
+
+	if d.pos < len(d.data) && d.data[d.pos] == '.' {
+		isFloat = true
+		d.pos++
+		if d.skipDigits() == 0 {
+			return false, d.syntaxError("after decimal point in numeric literal")
+		}
+	}
+
+	if d.pos < len(d.data) && (d.data[d.pos] == 'e' || d.data[d.pos] == 'E') {
+		isFloat = true
+		d.pos++
+		if d.pos < len(d.data) && (d.data[d.pos] == '+' || d.data[d.pos] == '-') {
+			d.pos++
+		}
+		if d.skipDigits() == 0 {
+			return false, d.syntaxError("in exponent of numeric literal")
+		}
+	}
+
+	return isFloat, nil
+}
+
+func isFiniteValue(v interface{}) bool {
+	f, ok := v.(float64)
+	return !ok || (!math.IsInf(f, 0) && !math.IsNaN(f))
+}
+
+func (d *decoder) skipDigits() int {
+	start := d.pos
+	for d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '9' {
+		d.pos++
+	}
+	return d.pos - start
+}
+
+func (d *decoder) string() (string, error) {
+	d.pos++ // '"'
+	start := d.pos
+
+	// fast path: no escapes and valid UTF-8
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+
+		if c == '"' {
+			if d.opts.MaxStringLength > 0 && d.pos-start > d.opts.MaxStringLength {
+				return "", d.limitError(LIMIT_STRING, d.opts.MaxStringLength)
+			}
+			s := string(d.data[start:d.pos])
+			d.pos++
+			return s, nil
+		}
+
+		if c == '\\' || c < 0x20 {
+			break
+		}
+
+		if c < utf8.RuneSelf {
+			d.pos++
+			continue
+		}
+
+		r, size := utf8.DecodeRune(d.data[d.pos:])
+		if r == utf8.RuneError && size == 1 {
+			break
+		}
+		d.pos += size
+	}
+
+	return d.slowString(start)
+}
+
+func (d *decoder) slowString(start int) (string, error) {
+	buf := append(d.scratch[:0], d.data[start:d.pos]...)
+
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+
+		switch {
+		case c == '"':
+			d.pos++
+			d.scratch = buf
+			if d.opts.MaxStringLength > 0 && len(buf) > d.opts.MaxStringLength {
+				return "", d.limitError(LIMIT_STRING, d.opts.MaxStringLength)
+			}
+			return string(buf), nil
+
+		case c < 0x20:
+			return "", d.syntaxError("in string literal")
+
+		case c == '\\':
+			d.pos++
+			if d.pos >= len(d.data) {
+				return "", d.syntaxError("")
+			}
+
+			switch d.data[d.pos] {
+			case '"', '\\', '/':
+				buf = append(buf, d.data[d.pos])
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'u':
+				r, ok := d.hex4(d.pos + 1)
+				if !ok {
+					return "", d.syntaxError("in \\u hexadecimal character escape")
+				}
+				d.pos += 4
+
+				if utf16.IsSurrogate(r) {
+					r2, ok := rune(-1), false
+					if d.pos+2 < len(d.data) && d.data[d.pos+1] == '\\' && d.data[d.pos+2] == 'u' {
+						r2, ok = d.hex4(d.pos + 3)
+					}
+
+					if dec := utf16.DecodeRune(r, r2); ok && dec != utf8.RuneError {
+						d.pos += 6
+						r = dec
+					} else {
+						r = utf8.RuneError
+					}
+				}
+
+				var runeBuf [utf8.UTFMax]byte
+				n := utf8.EncodeRune(runeBuf[:], r)
+				buf = append(buf, runeBuf[:n]...)
+			default:
+				return "", d.syntaxError("in string escape code")
+			}
+			d.pos++
+
+		case c < utf8.RuneSelf:
+			buf = append(buf, c)
+			d.pos++
+
+		default:
+			r, size := utf8.DecodeRune(d.data[d.pos:])
+			if r == utf8.RuneError && size == 1 {
+				buf = append(buf, "\uFFFD"...)
+			} else {
+				buf = append(buf, d.data[d.pos:d.pos+size]...)
+			}
+			d.pos += size
+		}
+	}
+
+	return "", d.syntaxError("")
+}
+
+func (d *decoder) hex4(pos int) (rune, bool) {
+	if pos+4 > len(d.data) {
+		return 0, false
+	}
+
+	var r rune
+	for _, c := range d.data[pos : pos+4] {
+		switch {
+		case c >= '0' && c <= '9':
+			c = c - '0'
+		case c >= 'a' && c <= 'f':
+			c = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r*16 + rune(c)
+	}
+
+	return r, true
+}
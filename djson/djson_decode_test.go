@@ -0,0 +1,155 @@
+package djson
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var codecTestDoc = `{
+	"name": "Ricardo Longa",
+	"idade": 28,
+	"height": 1.78,
+	"tiny": 1e-9,
+	"big": 12345678901234567890,
+	"active": true,
+	"spouse": null,
+	"html": "<b>&amp;</b>",
+	"escaped": "line\nbreak \"quoted\" é😀 /",
+	"skills": ["Golang", "Android", {"level": 3, "tags": []}],
+	"address": {"city": "Seoul", "zip": "04524", "geo": {"lat": 37.5665, "lng": 126.978}}
+}`
+
+func TestNativeCodecSameOutput(t *testing.T) {
+	aJson := NewDJSON().Parse(codecTestDoc)
+
+	var data map[string]interface{}
+	d := json.NewDecoder(strings.NewReader(codecTestDoc))
+	d.UseNumber()
+	if err := d.Decode(&data); err != nil {
+		log.Fatal(err)
+	}
+	bObject := ParseObject(data)
+
+	if !aJson.Object.Equal(bObject) {
+		log.Fatal("native decoder differs from encoding/json")
+	}
+
+	jsonByte, _ := json.Marshal(ConverObjectToMap(aJson.Object))
+	if aJson.ToString() != string(jsonByte) {
+		log.Fatal("ToString() differs from encoding/json\n", aJson.ToString(), "\n", string(jsonByte))
+	}
+
+	jsonByte, _ = json.MarshalIndent(ConverObjectToMap(aJson.Object), "", "   ")
+	if aJson.Object.ToStringPretty() != string(jsonByte) {
+		log.Fatal("ToStringPretty() differs from encoding/json")
+	}
+
+	log.Println(aJson.ToString())
+}
+
+func TestNativeDecoderError(t *testing.T) {
+	docs := []string{
+		`{"a":}`, `{"a":1,}`, `[1,2`, `{"a" 1}`, `[01]`, `["\x"]`, `[tru]`, `{"a":"b}`, "[\"\x01\"]",
+	}
+
+	for _, doc := range docs {
+		if _, err := ParseToArray(doc); err == nil {
+			if _, err := ParseToObject(doc); err == nil {
+				log.Fatal("must fail: ", doc)
+			}
+		}
+
+		if json.Valid([]byte(doc)) {
+			log.Fatal("encoding/json accepts: ", doc)
+		}
+	}
+}
+
+func TestNativeDecoderTrailing(t *testing.T) {
+	for _, doc := range []string{`{"a":1} x`, `{"a":1}}`, `{"a":1} {"b":2}`} {
+		var data map[string]interface{}
+		if err := json.NewDecoder(strings.NewReader(doc)).Decode(&data); err != nil {
+			log.Fatal("json.Decoder rejects: ", doc)
+		}
+
+		aJson := NewDJSON().Parse(doc)
+		if aJson.GetAsInt("a") != 1 || aJson.HasKey("b") {
+			log.Fatal("Parse() must take the first value of: ", doc)
+		}
+
+		if NewDJSON().ParseLazy(doc).GetAsInt("a") != 1 {
+			log.Fatal("ParseLazy() must take the first value of: ", doc)
+		}
+
+		if _, err := NewDJSON().ParseWithOptions(doc, ParseOptions{}); err == nil {
+			log.Fatal("ParseWithOptions() must reject: ", doc)
+		}
+	}
+}
+
+func benchmarkDoc() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for idx := 0; idx < 200; idx++ {
+		if idx > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strings.Replace(codecTestDoc, `"idade": 28`, `"idade": `+strconv.Itoa(idx), 1))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func BenchmarkParseNative(b *testing.B) {
+	doc := benchmarkDoc()
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseToArray(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseEncodingJSON(b *testing.B) {
+	doc := benchmarkDoc()
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var data []interface{}
+		d := json.NewDecoder(strings.NewReader(doc))
+		d.UseNumber()
+		if err := d.Decode(&data); err != nil {
+			b.Fatal(err)
+		}
+		_ = ParseArray(data)
+	}
+}
+
+func BenchmarkToStringNative(b *testing.B) {
+	arr, _ := ParseToArray(benchmarkDoc())
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = arr.ToString()
+	}
+}
+
+func BenchmarkToStringEncodingJSON(b *testing.B) {
+	arr, _ := ParseToArray(benchmarkDoc())
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		jsonByte, _ := json.Marshal(ConvertArrayToSlice(arr))
+		_ = string(jsonByte)
+	}
+}
@@ -0,0 +1,156 @@
+package djson
+
+// Freeze marks the document as immutable. Put, Remove, ReplaceAt, FromFields
+// and the path mutators, DoPathFunc included, become no-ops (path mutators
+// return an error), and every DJSON handed out by Get, GetAsObject,
+// GetAsArray or Next is frozen too. It guards only the methods of DJSON: the
+// exported Object and Array fields, and the DO and DA in them, can still be
+// written to.
+
+func (m *DJSON) Freeze() *DJSON {
+	m.frozen = true
+	return m
+}
+
+func (m *DJSON) IsFrozen() bool {
+	return m.frozen
+}
+
+// Thaw returns a mutable deep copy of the document.
+
+func (m *DJSON) Thaw() *DJSON {
+	return m.Clone()
+}
+
+// With returns a frozen document in which the value at path is replaced by val.
+// Only the objects and arrays along the path are copied, every other subtree is
+// shared with m, so m must be frozen. An index equal to the array size appends
+// val.
+
+func (m *DJSON) With(path string, val interface{}) (*DJSON, error) {
+	if !m.frozen {
+		return nil, notFrozenError
+	}
+
+	tokens := PathTokenizer(path)
+	if len(tokens) == 0 {
+		return nil, invalidPathError
+	}
+
+	root, err := withCore(m.GetAsInterface(), val, false, tokens...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDJSON().Put(root).Freeze(), nil
+}
+
+// Without returns a frozen document in which the value at path is removed.
+// Like With, unchanged subtrees are shared with m, which must be frozen.
+
+func (m *DJSON) Without(path string) (*DJSON, error) {
+	if !m.frozen {
+		return nil, notFrozenError
+	}
+
+	tokens := PathTokenizer(path)
+	if len(tokens) == 0 {
+		return nil, invalidPathError
+	}
+
+	root, err := withCore(m.GetAsInterface(), nil, true, tokens...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDJSON().Put(root).Freeze(), nil
+}
+
+func withCore(node interface{}, val interface{}, remove bool, token ...interface{}) (interface{}, error) {
+	switch tkey := token[0].(type) {
+	case string:
+		do, ok := node.(*DO)
+		if !ok {
+			return nil, invalidPathError
+		}
+
+		ndo := do.shallowCopy()
+
+		if len(token) == 1 {
+			if remove {
+				ndo.Remove(tkey)
+			} else {
+				ndo.Put(tkey, val)
+			}
+			return ndo, nil
+		}
+
+		child, ok := do.Map[tkey]
+		if !ok {
+			return nil, invalidPathError
+		}
+
+		nchild, err := withCore(child, val, remove, token[1:]...)
+		if err != nil {
+			return nil, err
+		}
+
+		ndo.Map[tkey] = nchild
+		return ndo, nil
+
+	case int:
+		da, ok := node.(*DA)
+		if !ok || tkey < 0 {
+			return nil, invalidPathError
+		}
+
+		nda := da.shallowCopy()
+
+		if len(token) == 1 {
+			if remove {
+				nda.Remove(tkey)
+			} else if tkey == nda.Size() {
+				nda.PushBack(val)
+			} else if tkey < nda.Size() {
+				nda.ReplaceAt(tkey, val)
+			} else {
+				return nil, invalidPathError
+			}
+			return nda, nil
+		}
+
+		if tkey >= da.Size() {
+			return nil, invalidPathError
+		}
+
+		nchild, err := withCore(da.Element[tkey], val, remove, token[1:]...)
+		if err != nil {
+			return nil, err
+		}
+
+		nda.Element[tkey] = nchild
+		return nda, nil
+	}
+
+	return nil, invalidPathError
+}
+
+func (m *DO) shallowCopy() *DO {
+	m.load()
+
+	t := NewObject()
+	for k, v := range m.Map {
+		t.Map[k] = v
+	}
+	t.keys = m.Keys()
+	return t
+}
+
+func (m *DA) shallowCopy() *DA {
+	m.load()
+
+	t := NewArray()
+	t.Element = make([]interface{}, len(m.Element))
+	copy(t.Element, m.Element)
+	return t
+}
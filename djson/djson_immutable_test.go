@@ -0,0 +1,108 @@
+package djson
+
+import (
+	"log"
+	"testing"
+)
+
+func TestImmutableWith(t *testing.T) {
+	jsonDoc := `{
+		"name":"Ricardo Longa",
+		"skills":["Golang","Android"],
+		"address":{"city":"Seoul","zip":"04524"}
+	}`
+
+	aJson := NewDJSON().Parse(jsonDoc).Freeze()
+
+	bJson, err := aJson.With(`["skills"][1]`, "Kotlin")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if aJson.GetAsStringPath(`["skills"][1]`) != "Android" {
+		log.Fatal("original document changed")
+	}
+
+	if bJson.GetAsStringPath(`["skills"][1]`) != "Kotlin" {
+		log.Fatal("With() failed")
+	}
+
+	aAddr, _ := aJson.Object.GetAsObject("address")
+	bAddr, _ := bJson.Object.GetAsObject("address")
+	if aAddr != bAddr {
+		log.Fatal("unchanged subtree is not shared")
+	}
+
+	cJson, err := bJson.Without(`["address"]["zip"]`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cJson.GetAsStringPath(`["address"]["zip"]`) != "" || bJson.GetAsStringPath(`["address"]["zip"]`) != "04524" {
+		log.Fatal("Without() failed")
+	}
+
+	log.Println(aJson.ToString())
+	log.Println(bJson.ToString())
+	log.Println(cJson.ToString())
+
+	if _, err := aJson.With(`["name"]["first"]`, "Ricardo"); err == nil {
+		log.Fatal("With() must fail on invalid path")
+	}
+
+	dJson := aJson.Thaw()
+	if _, err := dJson.With(`["name"]`, "Hery Victor"); err == nil {
+		log.Fatal("With() must fail on a document not frozen")
+	}
+
+	if _, err := dJson.Without(`["name"]`); err == nil {
+		log.Fatal("Without() must fail on a document not frozen")
+	}
+}
+
+func TestFrozenRefusePut(t *testing.T) {
+	aJson := NewDJSON().Parse(`{"name":"Ricardo Longa","skills":["Golang"]}`).Freeze()
+
+	aJson.Put("name", "Hery Victor")
+	if aJson.GetAsString("name") != "Ricardo Longa" {
+		log.Fatal("frozen document accepted Put()")
+	}
+
+	sJson, ok := aJson.GetAsArray("skills")
+	if !ok || !sJson.IsFrozen() {
+		log.Fatal("shared subtree must be frozen")
+	}
+
+	sJson.Put("Java")
+	if sJson.Length() != 1 {
+		log.Fatal("frozen subtree accepted Put()")
+	}
+
+	if err := aJson.UpdatePath(`["name"]`, "Hery Victor"); err == nil {
+		log.Fatal("frozen document accepted UpdatePath()")
+	}
+
+	bJson := aJson.Thaw()
+	bJson.Put("name", "Hery Victor")
+	if bJson.GetAsString("name") != "Hery Victor" || aJson.GetAsString("name") != "Ricardo Longa" {
+		log.Fatal("Thaw() failed")
+	}
+
+	err := aJson.DoPathFunc(`["skills"]`, nil, func(da *DA, idx int, v interface{}) {}, func(do *DO, key string, v interface{}) {
+		do.Remove(key)
+	})
+	if err == nil || !aJson.HasKey("skills") {
+		log.Fatal("frozen document accepted DoPathFunc()")
+	}
+
+	if aJson.GetAsStringPath(`["skills"][0]`) != "Golang" {
+		log.Fatal("frozen document must be read by path")
+	}
+
+	aJson.FromFields(struct {
+		Name string `json:"name"`
+	}{Name: "Hery Victor"})
+	if aJson.GetAsString("name") != "Ricardo Longa" {
+		log.Fatal("frozen document accepted FromFields()")
+	}
+}
@@ -0,0 +1,310 @@
+package djson
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// In lazy mode nested objects and arrays are kept as raw bytes of the original
+// document and decoded one level at a time when they are first accessed.
+// The raw bytes are checked for syntax and limits at parse time, so loading
+// never fails. Accessing a lazy document materializes it, so it is not safe
+// for concurrent use even if only read.
+//
+// ToString does not emit untouched subtrees verbatim: it sorts keys and
+// escapes HTML like encoding/json, so it re-encodes them and writes what it
+// writes for an eagerly parsed document. Serialize and ToStringWith without
+// Indent, ASCIIOnly and SortKeys (and EscapeHTML if there is nothing to
+// escape) write them as they are in the input, spacing and escapes included,
+// unless eager decoding would change them: a subtree with a duplicate key,
+// which keeps one value, or a number out of the float64 range, which is
+// dropped, is re-encoded.
+//
+// Only the methods load: the exported Map of a DO and Element of a DA are
+// empty until then. Objects and arrays handed out by Get, GetAsObject and
+// GetAsArray are loaded, but values reached through Map or Element directly
+// may not be.
+
+type lazyRaw struct {
+	data         []byte
+	duplicateKey int
+	altered      bool // eager decoding drops part of data
+}
+
+// ParseLazy is Parse in lazy mode.
+
+func (m *DJSON) ParseLazy(doc string) *DJSON {
+	m.parseWithOptions(doc, ParseOptions{Lazy: true}, true)
+	return m
+}
+
+func (m *DO) load() {
+	if m.lazy == nil {
+		return
+	}
+
+	d := &decoder{
+		data: m.lazy.data,
+		opts: &ParseOptions{DuplicateKey: m.lazy.duplicateKey},
+		lazy: true,
+	}
+
+	obj, _ := d.object(0)
+
+	m.Map = obj.Map
+	m.keys = obj.keys
+	m.lazy = nil
+}
+
+func (m *DA) load() {
+	if m.lazy == nil {
+		return
+	}
+
+	d := &decoder{
+		data: m.lazy.data,
+		opts: &ParseOptions{},
+		lazy: true,
+	}
+
+	arr, _ := d.array(0)
+
+	m.Element = arr.Element
+	m.lazy = nil
+}
+
+// child decodes the value of an object member or array element.
+
+func (d *decoder) child(depth int) (interface{}, error) {
+	if !d.lazy || d.pos >= len(d.data) || (d.data[d.pos] != '{' && d.data[d.pos] != '[') {
+		return d.value(depth)
+	}
+
+	start := d.pos
+	d.altered = false
+
+	if err := d.skip(depth); err != nil {
+		return nil, err
+	}
+
+	raw := &lazyRaw{
+		data:         d.data[start:d.pos],
+		duplicateKey: d.opts.DuplicateKey,
+		altered:      d.altered,
+	}
+
+	if d.data[start] == '{' {
+		return &DO{lazy: raw}, nil
+	}
+
+	return &DA{lazy: raw}, nil
+}
+
+// skip checks the value at d.pos like value does without building it.
+
+func (d *decoder) skip(depth int) error {
+	if d.pos >= len(d.data) {
+		return d.syntaxError("")
+	}
+
+	switch c := d.data[d.pos]; c {
+	case '{':
+		if err := d.checkDepth(depth + 1); err != nil {
+			return err
+		}
+		return d.skipObject(depth + 1)
+	case '[':
+		if err := d.checkDepth(depth + 1); err != nil {
+			return err
+		}
+		return d.skipArray(depth + 1)
+	case '"':
+		return d.skipString()
+	case 't':
+		return d.literal("true")
+	case 'f':
+		return d.literal("false")
+	case 'n':
+		return d.literal("null")
+	default:
+		if c == '-' || (c >= '0' && c <= '9') {
+			_, err := d.skipNumber()
+			return err
+		}
+	}
+
+	return d.syntaxError("looking for beginning of value")
+}
+
+func (d *decoder) skipObject(depth int) error {
+	d.pos++ // '{'
+	d.skipSpace()
+
+	if d.pos < len(d.data) && d.data[d.pos] == '}' {
+		d.pos++
+		return nil
+	}
+
+	keys := make(map[string]bool) // the keys object would keep
+
+	for {
+		if d.pos >= len(d.data) || d.data[d.pos] != '"' {
+			return d.syntaxError("looking for beginning of object key string")
+		}
+
+		key, err := d.string()
+		if err != nil {
+			return err
+		}
+
+		exists := keys[key]
+		if exists {
+			d.altered = true
+		}
+
+		if !exists && d.opts.MaxObjectKeys > 0 && len(keys) >= d.opts.MaxObjectKeys {
+			return d.limitError(LIMIT_OBJECT_KEYS, d.opts.MaxObjectKeys)
+		}
+
+		d.skipSpace()
+		if d.pos >= len(d.data) || d.data[d.pos] != ':' {
+			return d.syntaxError("after object key")
+		}
+		d.pos++
+		d.skipSpace()
+
+		finite := true
+		if d.pos < len(d.data) && (d.data[d.pos] == '-' || (d.data[d.pos] >= '0' && d.data[d.pos] <= '9')) {
+			finite, err = d.skipNumber()
+		} else {
+			err = d.skip(depth)
+		}
+
+		if err != nil {
+			return d.unwind(err, key)
+		}
+
+		// non-finite numbers are dropped like object does
+		if !exists && finite {
+			keys[key] = true
+		}
+
+		d.skipSpace()
+		if d.pos >= len(d.data) {
+			return d.syntaxError("")
+		}
+
+		if d.data[d.pos] == ',' {
+			d.pos++
+			d.skipSpace()
+			continue
+		}
+
+		if d.data[d.pos] == '}' {
+			d.pos++
+			return nil
+		}
+
+		return d.syntaxError("after object key:value pair")
+	}
+}
+
+func (d *decoder) skipArray(depth int) error {
+	d.pos++ // '['
+	d.skipSpace()
+
+	if d.pos < len(d.data) && d.data[d.pos] == ']' {
+		d.pos++
+		return nil
+	}
+
+	for idx := 0; ; idx++ {
+		if d.opts.MaxArrayLength > 0 && idx >= d.opts.MaxArrayLength {
+			return d.limitError(LIMIT_ARRAY, d.opts.MaxArrayLength)
+		}
+
+		if err := d.skip(depth); err != nil {
+			return d.unwind(err, idx)
+		}
+
+		d.skipSpace()
+		if d.pos >= len(d.data) {
+			return d.syntaxError("")
+		}
+
+		if d.data[d.pos] == ',' {
+			d.pos++
+			d.skipSpace()
+			continue
+		}
+
+		if d.data[d.pos] == ']' {
+			d.pos++
+			return nil
+		}
+
+		return d.syntaxError("after array element")
+	}
+}
+
+// skipNumber scans a number and tells whether it is finite as a float64;
+// object and array drop one which is not.
+
+func (d *decoder) skipNumber() (bool, error) {
+	start := d.pos
+
+	if _, err := d.scanNumber(); err != nil {
+		return false, err
+	}
+
+	num := d.data[start:d.pos]
+	if len(num) <= 308 && bytes.IndexAny(num, "eE") < 0 {
+		return true, nil
+	}
+
+	if f, _ := strconv.ParseFloat(string(num), 64); math.IsInf(f, 0) {
+		d.altered = true
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// skipString scans a string without allocating unless it has escapes.
+
+func (d *decoder) skipString() error {
+	d.pos++ // '"'
+	start := d.pos
+
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+
+		if c == '"' {
+			if d.opts.MaxStringLength > 0 && d.pos-start > d.opts.MaxStringLength {
+				return d.limitError(LIMIT_STRING, d.opts.MaxStringLength)
+			}
+			d.pos++
+			return nil
+		}
+
+		if c == '\\' || c < 0x20 {
+			break
+		}
+
+		if c < utf8.RuneSelf {
+			d.pos++
+			continue
+		}
+
+		r, size := utf8.DecodeRune(d.data[d.pos:])
+		if r == utf8.RuneError && size == 1 {
+			break
+		}
+		d.pos += size
+	}
+
+	_, err := d.slowString(start)
+	return err
+}
@@ -0,0 +1,129 @@
+package djson
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestParseLazy(t *testing.T) {
+	doc := `{"id":7,"meta":{ "b": 1, "a": [1, 2] },"items":[{"name":"x"},{"name":"y"}],"tag":"<t>"}`
+
+	aJson := NewDJSON().ParseLazy(doc)
+	bJson := NewDJSON().Parse(doc)
+
+	if aJson.GetAsInt("id") != 7 {
+		log.Fatal("id must be 7")
+	}
+
+	// meta and items are untouched and written as they were
+	if out := aJson.ToStringWith(SerializeOptions{}); out != `{"id":7,"meta":{ "b": 1, "a": [1, 2] },"items":[{"name":"x"},{"name":"y"}],"tag":"<t>"}` {
+		log.Fatal("untouched subtree must be verbatim: ", out)
+	}
+
+	// but sorted and HTML-escaped as if parsed eagerly
+	for _, d := range []string{doc, `{"q":{"b":1,"a":"<"}}`} {
+		if lazy, eager := NewDJSON().ParseLazy(d).ToString(), NewDJSON().Parse(d).ToString(); lazy != eager {
+			log.Fatal("lazy must be written as eager: ", lazy, " ", eager)
+		}
+	}
+
+	// handed out objects are loaded
+	if mo, ok := NewDJSON().ParseLazy(doc).Object.GetAsObject("meta"); !ok || len(mo.Map) != 2 {
+		log.Fatal("meta must be loaded")
+	}
+
+	if aJson.GetAsStringPath(`["items"][1]["name"]`) != "y" {
+		log.Fatal("items[1].name must be y")
+	}
+
+	aJson.UpdatePath(`["meta"]["b"]`, 2)
+
+	if out := aJson.ToStringWith(SerializeOptions{}); out != `{"id":7,"meta":{"b":2,"a":[1, 2]},"items":[{"name":"x"},{"name":"y"}],"tag":"<t>"}` {
+		log.Fatal("touched subtree must be encoded: ", out)
+	}
+
+	if aJson.Object.ToStringPretty() != NewDJSON().Parse(aJson.ToString()).Object.ToStringPretty() {
+		log.Fatal("pretty output must be re-encoded")
+	}
+
+	bJson.UpdatePath(`["meta"]["b"]`, 2)
+	if !aJson.Equal(bJson) {
+		log.Fatal("lazy and eager must be equal")
+	}
+
+	log.Println(aJson.ToString())
+}
+
+func TestParseLazyAltered(t *testing.T) {
+	// a duplicate key or a number out of range is not written as it was
+	doc := `{"a":{"x":1, "x":2},"b":[1, 1e999],"c":{ "y": 1 }}`
+
+	lazy := NewDJSON().ParseLazy(doc).ToStringWith(SerializeOptions{})
+	if lazy != `{"a":{"x":2},"b":[1,null],"c":{ "y": 1 }}` {
+		log.Fatal("altered subtrees must be re-encoded: ", lazy)
+	}
+
+	if eager := NewDJSON().Parse(doc).ToStringWith(SerializeOptions{}); eager != `{"a":{"x":2},"b":[1,null],"c":{"y":1}}` {
+		log.Fatal("unexpected eager output: ", eager)
+	}
+
+	// keys count as the object keeps them
+	docs := map[string]bool{
+		`{"a":{"x":1,"x":2,"y":3}}`:       true,
+		`{"a":{"x":1e999,"y":1,"z":2}}`:   true,
+		`{"a":{"x":1,"y":2,"z":3}}`:       false,
+		`{"a":{"x":1,"y":2,"y":3,"z":4}}`: false,
+	}
+
+	for d, ok := range docs {
+		_, err := NewDJSON().ParseWithOptions(d, ParseOptions{MaxObjectKeys: 2})
+		_, lerr := NewDJSON().ParseWithOptions(d, ParseOptions{MaxObjectKeys: 2, Lazy: true})
+
+		if (err == nil) != ok || (lerr == nil) != ok {
+			log.Fatal("wrong key limit for ", d, ": ", err, ", ", lerr)
+		}
+	}
+}
+
+func TestParseLazyError(t *testing.T) {
+	if NewDJSON().ParseLazy(`{"a":{"b":[1,}}`).IsObject() {
+		log.Fatal("syntax error in nested value must fail")
+	}
+
+	_, err := NewDJSON().ParseWithOptions(`{"a":{"b":["`+strings.Repeat("x", 10)+`"]}}`, ParseOptions{MaxStringLength: 4, Lazy: true})
+
+	var lerr *LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != LIMIT_STRING || lerr.Path != `["a"]["b"][0]` {
+		log.Fatal("expected string limit at [\"a\"][\"b\"][0] but ", err)
+	}
+
+	log.Println(err)
+}
+
+func BenchmarkParseLazyTwoFields(b *testing.B) {
+	doc := `{"id":1,"type":"order","payload":` + benchmarkDoc() + `}`
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		aJson := NewDJSON().ParseLazy(doc)
+		_ = aJson.GetAsInt("id")
+		_ = aJson.GetAsString("type")
+	}
+}
+
+func BenchmarkParseEagerTwoFields(b *testing.B) {
+	doc := `{"id":1,"type":"order","payload":` + benchmarkDoc() + `}`
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		aJson := NewDJSON().Parse(doc)
+		_ = aJson.GetAsInt("id")
+		_ = aJson.GetAsString("type")
+	}
+}
@@ -0,0 +1,101 @@
+package djson
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DUPLICATE_KEY_LAST_WINS = iota // same as encoding/json
+	DUPLICATE_KEY_FIRST_WINS
+	DUPLICATE_KEY_ERROR
+)
+
+const (
+	LIMIT_DEPTH         = "depth"
+	LIMIT_BYTES         = "bytes"
+	LIMIT_STRING        = "string"
+	LIMIT_ARRAY         = "array"
+	LIMIT_OBJECT_KEYS   = "object keys"
+	LIMIT_DUPLICATE_KEY = "duplicate key"
+)
+
+// ParseOptions bounds the resources spent on untrusted input.
+// A zero value for a limit means unlimited. Lazy keeps nested objects and
+// arrays undecoded until accessed (see ParseLazy); it is ignored with
+// DUPLICATE_KEY_ERROR.
+
+type ParseOptions struct {
+	MaxDepth        int
+	MaxBytes        int
+	MaxStringLength int
+	MaxArrayLength  int
+	MaxObjectKeys   int
+	DuplicateKey    int
+	Lazy            bool
+}
+
+// LimitError is returned by ParseWithOptions when the document exceeds one of
+// the limits in ParseOptions or has a duplicate key under DUPLICATE_KEY_ERROR.
+
+type LimitError struct {
+	Limit string
+	Max   int
+	Path  string
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == LIMIT_DUPLICATE_KEY {
+		return fmt.Sprintf("duplicate key at %s", e.Path)
+	}
+
+	return fmt.Sprintf("%s limit %d exceeded at %s", e.Limit, e.Max, e.Path)
+}
+
+// ParseWithOptions parses doc like Parse but enforces opts and reports errors,
+// and unlike Parse it rejects anything after the top-level value. On error m
+// is left unchanged.
+
+func (m *DJSON) ParseWithOptions(doc string, opts ParseOptions) (*DJSON, error) {
+	return m.parseWithOptions(doc, opts, false)
+}
+
+// parseWithOptions ignores what follows the top-level value if trailing.
+
+func (m *DJSON) parseWithOptions(doc string, opts ParseOptions, trailing bool) (*DJSON, error) {
+	if m.JsonType != JSON_NULL || m.frozen {
+		return m, nil
+	}
+
+	if opts.MaxBytes > 0 && len(doc) > opts.MaxBytes {
+		return m, &LimitError{Limit: LIMIT_BYTES, Max: opts.MaxBytes}
+	}
+
+	tdoc := strings.TrimSpace(doc)
+	if tdoc == "" {
+		return m, nil
+	}
+
+	if tdoc[0] != '{' && tdoc[0] != '[' {
+		if opts.MaxStringLength > 0 && len(tdoc) > opts.MaxStringLength {
+			return m, &LimitError{Limit: LIMIT_STRING, Max: opts.MaxStringLength}
+		}
+		return m.Parse(tdoc), nil
+	}
+
+	v, err := decode([]byte(tdoc), &opts, trailing)
+	if err != nil {
+		return m, err
+	}
+
+	switch t := v.(type) {
+	case *DO:
+		m.Object = t
+		m.JsonType = JSON_OBJECT
+	case *DA:
+		m.Array = t
+		m.JsonType = JSON_ARRAY
+	}
+
+	return m, nil
+}
@@ -0,0 +1,65 @@
+package djson
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestParseWithOptions(t *testing.T) {
+	opts := ParseOptions{
+		MaxDepth:        3,
+		MaxBytes:        1024,
+		MaxStringLength: 16,
+		MaxArrayLength:  4,
+		MaxObjectKeys:   4,
+	}
+
+	aJson, err := NewDJSON().ParseWithOptions(`{"name":"Ricardo Longa","skills":["Golang","Android"],"idade":28}`, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println(aJson.ToString())
+
+	cases := map[string]string{
+		`[[[[1]]]]`:                                     LIMIT_DEPTH,
+		`["` + strings.Repeat("a", 17) + `"]`:           LIMIT_STRING,
+		`[1,2,3,4,5]`:                                   LIMIT_ARRAY,
+		`{"a":1,"b":2,"c":3,"d":4,"e":5}`:               LIMIT_OBJECT_KEYS,
+		`[` + strings.Repeat(`"aaaaaaaa",`, 100) + `1]`: LIMIT_BYTES,
+	}
+
+	for doc, limit := range cases {
+		_, err := NewDJSON().ParseWithOptions(doc, opts)
+
+		var lerr *LimitError
+		if !errors.As(err, &lerr) || lerr.Limit != limit {
+			log.Fatal("expected limit error ", limit, " but ", err)
+		}
+
+		log.Println(err)
+	}
+}
+
+func TestParseDuplicateKey(t *testing.T) {
+	doc := `{"name":"first","name":"last"}`
+
+	aJson, _ := NewDJSON().ParseWithOptions(doc, ParseOptions{})
+	if aJson.GetAsString("name") != "last" {
+		log.Fatal("last must win")
+	}
+
+	aJson, _ = NewDJSON().ParseWithOptions(doc, ParseOptions{DuplicateKey: DUPLICATE_KEY_FIRST_WINS})
+	if aJson.GetAsString("name") != "first" {
+		log.Fatal("first must win")
+	}
+
+	_, err := NewDJSON().ParseWithOptions(doc, ParseOptions{DuplicateKey: DUPLICATE_KEY_ERROR})
+	if err == nil {
+		log.Fatal("duplicate key must fail")
+	}
+
+	log.Println(err)
+}
@@ -1,10 +1,19 @@
 package djson
 
+import (
+	"time"
+)
+
+func (m *DJSON) GetPath(path string) (*DJSON, bool) {
+	return m.getByTokens(PathTokenizer(path)...)
+}
+
 func (m *DJSON) GetAsObjectPath(path string) (*DJSON, bool) {
 
 	retJson := NewDJSON()
+	retJson.frozen = m.frozen
 
-	err := m.DoPathFunc(path, nil,
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			if obj, ok := da.GetAsObject(idx); ok {
 				retJson.Object = obj
@@ -29,8 +38,9 @@ func (m *DJSON) GetAsObjectPath(path string) (*DJSON, bool) {
 func (m *DJSON) GetAsArrayPath(path string) (*DJSON, bool) {
 
 	retJson := NewDJSON()
+	retJson.frozen = m.frozen
 
-	err := m.DoPathFunc(path, nil,
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			if arr, ok := da.GetAsArray(idx); ok {
 				retJson.Array = arr
@@ -56,7 +66,7 @@ func (m *DJSON) GetAsFloatPath(path string, defFloat ...float64) float64 {
 	var retFloat float64
 	var ok bool
 
-	err := m.DoPathFunc(path, nil,
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			retFloat, ok = da.GetAsFloat(idx)
 		},
@@ -80,7 +90,7 @@ func (m *DJSON) GetAsIntPath(path string, defInt ...int64) int64 {
 	var retInt int64
 	var ok bool
 
-	err := m.DoPathFunc(path, nil,
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			retInt, ok = da.GetAsInt(idx)
 		},
@@ -105,7 +115,7 @@ func (m *DJSON) GetAsBoolPath(path string, defBool ...bool) bool {
 	var retBool bool
 	var ok bool
 
-	err := m.DoPathFunc(path, nil,
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			retBool, ok = da.GetAsBool(idx)
 		},
@@ -128,7 +138,7 @@ func (m *DJSON) GetAsBoolPath(path string, defBool ...bool) bool {
 func (m *DJSON) GetAsStringPath(path string) string {
 	var retStr string
 
-	_ = m.DoPathFunc(path, nil,
+	_ = m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			retStr = da.GetAsString(idx)
 		},
@@ -143,7 +153,7 @@ func (m *DJSON) GetAsStringPath(path string) string {
 func (m *DJSON) GetTypePath(path string) string {
 	var pathType string
 
-	_ = m.DoPathFunc(path, nil,
+	_ = m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			pathType, _ = da.GetType(idx)
 		},
@@ -156,9 +166,13 @@ func (m *DJSON) GetTypePath(path string) string {
 }
 
 func (m *DJSON) SortObjectArrayPath(path string, isAsc bool, okey string) error {
+	if m.frozen {
+		return frozenError
+	}
+
 	var isSorted bool
 
-	err := m.DoPathFunc(path, nil,
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			if tda, ok := da.GetAsArray(idx); ok {
 				isSorted = tda.SortObject(isAsc, okey)
@@ -191,9 +205,13 @@ func (m *DJSON) SortObjectArrayDescPath(path string, key string) error {
 }
 
 func (m *DJSON) SortPath(path string, isAsc bool) error {
+	if m.frozen {
+		return frozenError
+	}
+
 	var isSorted bool
 
-	err := m.DoPathFunc(path, nil,
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			if tda, ok := da.GetAsArray(idx); ok {
 				isSorted = tda.Sort(isAsc)
@@ -226,7 +244,13 @@ func (m *DJSON) SortAscPath(path string) error {
 }
 
 func (m *DJSON) RemovePath(path string) error {
-	return m.DoPathFunc(path, nil,
+	if m.frozen {
+		return frozenError
+	}
+
+	done := m.trackRemoval(PathTokenizer(path)...)
+
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			da.Remove(idx)
 		},
@@ -234,10 +258,18 @@ func (m *DJSON) RemovePath(path string) error {
 			do.Remove(key)
 		},
 	)
+
+	done(err == nil)
+
+	return err
 }
 
 func (m *DJSON) PutNewObjectPath(path string, okey string, oval interface{}) error {
-	return m.DoPathFunc(path, oval,
+	if m.frozen {
+		return frozenError
+	}
+
+	return m.doPathFunc(path, oval,
 		func(da *DA, idx int, v interface{}) {
 			da.Insert(idx, Object{okey: v})
 		},
@@ -250,7 +282,11 @@ func (m *DJSON) PutNewObjectPath(path string, okey string, oval interface{}) err
 // Replace or insert values as array
 
 func (m *DJSON) PutNewArrayPath(path string, val ...interface{}) error {
-	return m.DoPathFunc(path, val,
+	if m.frozen {
+		return frozenError
+	}
+
+	return m.doPathFunc(path, val,
 		func(da *DA, idx int, v interface{}) {
 			da.Insert(idx, v)
 		},
@@ -264,7 +300,11 @@ func (m *DJSON) PutNewArrayPath(path string, val ...interface{}) error {
 // The path must indicate array.
 
 func (m *DJSON) PushBackPath(path string, val interface{}) error {
-	return m.DoPathFunc(path, val,
+	if m.frozen {
+		return frozenError
+	}
+
+	return m.doPathFunc(path, val,
 		func(da *DA, idx int, v interface{}) {
 			if dda, ok := da.GetAsArray(idx); ok {
 				dda.PushBack(v)
@@ -281,7 +321,13 @@ func (m *DJSON) PushBackPath(path string, val interface{}) error {
 // Replace or insert a value
 
 func (m *DJSON) UpdatePath(path string, val interface{}) error {
-	return m.DoPathFunc(path, val,
+	if m.frozen {
+		return frozenError
+	}
+
+	done := m.trackChange(PathTokenizer(path)...)
+
+	err := m.doPathFunc(path, val,
 		func(da *DA, idx int, v interface{}) {
 			da.ReplaceAt(idx, v)
 		},
@@ -289,6 +335,10 @@ func (m *DJSON) UpdatePath(path string, val interface{}) error {
 			do.Put(key, v)
 		},
 	)
+
+	done(err == nil)
+
+	return err
 }
 
 func (m *DJSON) doPathFuncCore(
@@ -313,11 +363,12 @@ func (m *DJSON) doPathFuncCore(
 				objectTaskFunc(dObject, tkey, val)
 				return nil
 			} else {
-				if _, ok := dObject.Map[tkey]; !ok {
+				child, ok := dObject.Get(tkey)
+				if !ok {
 					return invalidPathError
 				}
 
-				switch t := dObject.Map[tkey].(type) {
+				switch t := child.(type) {
 				case *DO:
 					dObject = t
 					dArray = nil
@@ -366,6 +417,18 @@ func (m *DJSON) doPathFuncCore(
 }
 
 func (m *DJSON) DoPathFunc(path string, val interface{},
+	arrayTaskFunc func(da *DA, idx int, v interface{}),
+	objectTaskFunc func(do *DO, key string, v interface{})) error {
+	if m.frozen {
+		return frozenError
+	}
+
+	return m.doPathFunc(path, val, arrayTaskFunc, objectTaskFunc)
+}
+
+// doPathFunc is DoPathFunc for the methods here, which guard their writes.
+
+func (m *DJSON) doPathFunc(path string, val interface{},
 	arrayTaskFunc func(da *DA, idx int, v interface{}),
 	objectTaskFunc func(do *DO, key string, v interface{})) error {
 	return m.doPathFuncCore(arrayTaskFunc, objectTaskFunc, val, PathTokenizer(path)...)
@@ -374,19 +437,15 @@ func (m *DJSON) DoPathFunc(path string, val interface{},
 func (m *DJSON) GetKeysPath(path string) ([]string, error) {
 	rk := make([]string, 0)
 
-	err := m.DoPathFunc(path, nil,
+	err := m.doPathFunc(path, nil,
 		func(da *DA, idx int, v interface{}) {
 			if ddo, ok := da.GetAsObject(idx); ok {
-				for k := range ddo.Map {
-					rk = append(rk, k)
-				}
+				rk = append(rk, ddo.Keys()...)
 			}
 		},
 		func(do *DO, key string, v interface{}) {
 			if ddo, ok := do.GetAsObject(key); ok {
-				for k := range ddo.Map {
-					rk = append(rk, k)
-				}
+				rk = append(rk, ddo.Keys()...)
 			}
 		},
 	)
@@ -397,3 +456,43 @@ func (m *DJSON) GetKeysPath(path string) ([]string, error) {
 
 	return rk, nil
 }
+
+func (m *DJSON) GetAsTimePath(path string, layouts ...string) (time.Time, bool) {
+	if v, ok := m.GetPath(path); ok {
+		return v.GetAsTime("", layouts...)
+	}
+
+	return time.Time{}, false
+}
+
+func (m *DJSON) GetAsDurationPath(path string) (time.Duration, bool) {
+	if v, ok := m.GetPath(path); ok {
+		return v.GetAsDuration()
+	}
+
+	return 0, false
+}
+
+func (m *DJSON) GetAsBytesPath(path string, encoding ...int) ([]byte, bool) {
+	if v, ok := m.GetPath(path); ok {
+		return v.GetAsBytes("", encoding...)
+	}
+
+	return nil, false
+}
+
+func (m *DJSON) GetAsStringSlicePath(path string) ([]string, bool) {
+	if v, ok := m.GetPath(path); ok {
+		return v.GetAsStringSlice()
+	}
+
+	return nil, false
+}
+
+func (m *DJSON) GetAsIntSlicePath(path string) ([]int64, bool) {
+	if v, ok := m.GetPath(path); ok {
+		return v.GetAsIntSlice()
+	}
+
+	return nil, false
+}
@@ -0,0 +1,1013 @@
+package djson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Query is a compiled jq-style expression. It is safe for concurrent use.
+//
+// Supported syntax: . .foo ."foo" .[n] .[a:b] .[] .. ? | , // and or
+// == != < <= > >= + - * / % [..] {..} "\(..)" if-then-elif-else-end
+// try-catch, reduce, `as $x |` and the built-ins in djson_query_func.go.
+
+type Query struct {
+	source string
+	f      queryFunc
+}
+
+type queryFunc func(in interface{}, vars *queryVars) ([]interface{}, error)
+
+type queryVars struct {
+	name   string
+	value  interface{}
+	parent *queryVars
+}
+
+func (m *queryVars) lookup(name string) (interface{}, bool) {
+	for v := m; v != nil; v = v.parent {
+		if v.name == name {
+			return v.value, true
+		}
+	}
+	return nil, false
+}
+
+func CompileQuery(expr string) (*Query, error) {
+	f, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Query{source: expr, f: f}, nil
+}
+
+func MustCompileQuery(expr string) *Query {
+	q, err := CompileQuery(expr)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func (q *Query) String() string {
+	return q.source
+}
+
+// Run returns every output of the query. Outputs never share objects or
+// arrays with in.
+
+func (q *Query) Run(in *DJSON) ([]*DJSON, error) {
+	var root interface{}
+	if in != nil {
+		root = in.GetAsInterface()
+	}
+
+	outs, err := q.f(root, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rets := make([]*DJSON, 0, len(outs))
+	for idx := range outs {
+		rets = append(rets, NewDJSON().Put(cloneValue(outs[idx])))
+	}
+
+	return rets, nil
+}
+
+// RunOne returns the first output of the query, or a null DJSON if there is none.
+
+func (q *Query) RunOne(in *DJSON) (*DJSON, error) {
+	rets, err := q.Run(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rets) == 0 {
+		return NewDJSON(), nil
+	}
+
+	return rets[0], nil
+}
+
+func (m *DJSON) Query(expr string) ([]*DJSON, error) {
+	q, err := CompileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Run(m)
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *DO:
+		return t.Clone()
+	case *DA:
+		return t.Clone()
+	}
+	return v
+}
+
+// lexer
+
+const (
+	qtEOF = iota
+	qtNum
+	qtStr
+	qtIdent
+	qtVar
+	qtField
+	qtOp
+)
+
+type queryToken struct {
+	kind  int
+	text  string
+	num   interface{}
+	parts []queryStrPart
+	pos   int
+}
+
+type queryStrPart struct {
+	lit    string
+	expr   string
+	isExpr bool
+}
+
+var queryOps = []string{"//", "==", "!=", "<=", ">=", "..", "|", ",", ".", "[", "]", "{", "}", "(", ")", ":", ";", "?", "<", ">", "+", "-", "*", "/", "%"}
+
+func isIdentByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func lexQuery(src string) ([]queryToken, error) {
+	tokens := make([]queryToken, 0)
+
+	for pos := 0; ; {
+		for pos < len(src) && (src[pos] == ' ' || src[pos] == '\t' || src[pos] == '\n' || src[pos] == '\r') {
+			pos++
+		}
+
+		if pos < len(src) && src[pos] == '#' {
+			for pos < len(src) && src[pos] != '\n' {
+				pos++
+			}
+			continue
+		}
+
+		if pos >= len(src) {
+			return append(tokens, queryToken{kind: qtEOF, pos: pos}), nil
+		}
+
+		start := pos
+		c := src[pos]
+
+		switch {
+		case c == '"':
+			parts, end, err := lexQueryString(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: qtStr, parts: parts, pos: start})
+			pos = end
+
+		case c >= '0' && c <= '9':
+			for pos < len(src) && src[pos] >= '0' && src[pos] <= '9' {
+				pos++
+			}
+			isFloat := false
+			if pos+1 < len(src) && src[pos] == '.' && src[pos+1] >= '0' && src[pos+1] <= '9' {
+				isFloat = true
+				pos++
+				for pos < len(src) && src[pos] >= '0' && src[pos] <= '9' {
+					pos++
+				}
+			}
+			if pos < len(src) && (src[pos] == 'e' || src[pos] == 'E') {
+				isFloat = true
+				pos++
+				if pos < len(src) && (src[pos] == '+' || src[pos] == '-') {
+					pos++
+				}
+				for pos < len(src) && src[pos] >= '0' && src[pos] <= '9' {
+					pos++
+				}
+			}
+
+			text := src[start:pos]
+			var num interface{}
+			if i, err := strconv.ParseInt(text, 10, 64); err == nil && !isFloat {
+				num = i
+			} else if f, err := strconv.ParseFloat(text, 64); err == nil {
+				num = f
+			} else {
+				return nil, queryErrorAt(start, "invalid number "+text)
+			}
+			tokens = append(tokens, queryToken{kind: qtNum, text: text, num: num, pos: start})
+
+		case isIdentByte(c, true):
+			for pos < len(src) && isIdentByte(src[pos], false) {
+				pos++
+			}
+			tokens = append(tokens, queryToken{kind: qtIdent, text: src[start:pos], pos: start})
+
+		case c == '$' && pos+1 < len(src) && isIdentByte(src[pos+1], true):
+			pos++
+			for pos < len(src) && isIdentByte(src[pos], false) {
+				pos++
+			}
+			tokens = append(tokens, queryToken{kind: qtVar, text: src[start+1 : pos], pos: start})
+
+		case c == '.' && pos+1 < len(src) && isIdentByte(src[pos+1], true):
+			pos++
+			for pos < len(src) && isIdentByte(src[pos], false) {
+				pos++
+			}
+			tokens = append(tokens, queryToken{kind: qtField, text: src[start+1 : pos], pos: start})
+
+		default:
+			matched := false
+			for _, op := range queryOps {
+				if strings.HasPrefix(src[pos:], op) {
+					tokens = append(tokens, queryToken{kind: qtOp, text: op, pos: start})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, queryErrorAt(start, fmt.Sprintf("unexpected character %q", c))
+			}
+		}
+	}
+}
+
+// lexQueryString reads a string literal at pos and returns its parts.
+// \( expr ) parts are kept as source and compiled by the parser.
+
+func lexQueryString(src string, pos int) ([]queryStrPart, int, error) {
+	start := pos
+	pos++ // '"'
+
+	parts := make([]queryStrPart, 0)
+	var sb strings.Builder
+
+	for pos < len(src) {
+		c := src[pos]
+
+		if c == '"' {
+			if sb.Len() > 0 || len(parts) == 0 {
+				parts = append(parts, queryStrPart{lit: sb.String()})
+			}
+			return parts, pos + 1, nil
+		}
+
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(src[pos:])
+			sb.WriteRune(r)
+			pos += size
+			continue
+		}
+
+		pos++
+		if pos >= len(src) {
+			break
+		}
+
+		switch src[pos] {
+		case '"', '\\', '/':
+			sb.WriteByte(src[pos])
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'u':
+			if pos+5 > len(src) {
+				return nil, 0, queryErrorAt(pos, "invalid \\u escape")
+			}
+			r, err := strconv.ParseUint(src[pos+1:pos+5], 16, 32)
+			if err != nil {
+				return nil, 0, queryErrorAt(pos, "invalid \\u escape")
+			}
+			sb.WriteRune(rune(r))
+			pos += 4
+		case '(':
+			end, err := matchQueryParen(src, pos)
+			if err != nil {
+				return nil, 0, err
+			}
+			if sb.Len() > 0 {
+				parts = append(parts, queryStrPart{lit: sb.String()})
+				sb.Reset()
+			}
+			parts = append(parts, queryStrPart{expr: src[pos+1 : end], isExpr: true})
+			pos = end
+		default:
+			return nil, 0, queryErrorAt(pos, "invalid escape")
+		}
+		pos++
+	}
+
+	return nil, 0, queryErrorAt(start, "unterminated string")
+}
+
+// matchQueryParen returns the position of the ')' closing the '(' at pos.
+
+func matchQueryParen(src string, pos int) (int, error) {
+	depth := 0
+	for idx := pos; idx < len(src); idx++ {
+		switch src[idx] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return idx, nil
+			}
+		case '"':
+			for idx++; idx < len(src) && src[idx] != '"'; idx++ {
+				if src[idx] == '\\' {
+					idx++
+				}
+			}
+		}
+	}
+	return 0, queryErrorAt(pos, "unterminated interpolation")
+}
+
+func queryErrorAt(pos int, msg string) error {
+	return fmt.Errorf("%w: %s at %d", invalidQueryError, msg, pos)
+}
+
+// parser
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func parseQuery(src string) (queryFunc, error) {
+	tokens, err := lexQuery(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+
+	f, err := p.parsePipe(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != qtEOF {
+		return nil, p.errorf("unexpected %s", p.peek().describe())
+	}
+
+	return f, nil
+}
+
+func (m queryToken) describe() string {
+	switch m.kind {
+	case qtEOF:
+		return "end of query"
+	case qtStr:
+		return "string"
+	case qtVar:
+		return "$" + m.text
+	case qtField:
+		return "." + m.text
+	}
+	return strconv.Quote(m.text)
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.pos]
+	if t.kind != qtEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == qtOp && t.text == op
+}
+
+func (p *queryParser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == qtIdent && t.text == kw
+}
+
+func (p *queryParser) expectOp(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q but %s", op, p.peek().describe())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *queryParser) expectKeyword(kw string) error {
+	if !p.isKeyword(kw) {
+		return p.errorf("expected %q but %s", kw, p.peek().describe())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return queryErrorAt(p.peek().pos, fmt.Sprintf(format, args...))
+}
+
+// parsePipe parses a | b | c. noComma is used for object values.
+
+func (p *queryParser) parsePipe(noComma bool) (queryFunc, error) {
+	left, err := p.parseComma(noComma)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("|") {
+		p.pos++
+		right, err := p.parseComma(noComma)
+		if err != nil {
+			return nil, err
+		}
+		left = pipeQuery(left, right)
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseComma(noComma bool) (queryFunc, error) {
+	left, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+
+	for !noComma && p.isOp(",") {
+		p.pos++
+		right, err := p.parseAlt()
+		if err != nil {
+			return nil, err
+		}
+		left = commaQuery(left, right)
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAlt() (queryFunc, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("//") {
+		p.pos++
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = altQuery(left, right)
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseOr() (queryFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicQuery(left, right, true)
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryFunc, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") {
+		p.pos++
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = logicQuery(left, right, false)
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseCompare() (queryFunc, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.isOp(op) {
+			p.pos++
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return binaryQuery(left, right, op), nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAdditive() (queryFunc, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("+") || p.isOp("-") {
+		op := p.next().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryQuery(left, right, op)
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseMultiplicative() (queryFunc, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryQuery(left, right, op)
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryFunc, error) {
+	if p.isOp("-") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryQuery(constQuery(int64(0)), operand, "-"), nil
+	}
+
+	return p.parsePostfix(true)
+}
+
+// parsePostfix parses a term with its suffixes. With bind, `term as $x | body`
+// is parsed too.
+
+func (p *queryParser) parsePostfix(bind bool) (queryFunc, error) {
+	term, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.peek().kind == qtField:
+			term = indexQuery(term, constQuery(p.next().text))
+
+		case p.isOp(".") && p.tokens[p.pos+1].kind == qtStr:
+			p.pos++
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			term = indexQuery(term, key)
+
+		case p.isOp("[") || (p.isOp(".") && p.tokens[p.pos+1].kind == qtOp && p.tokens[p.pos+1].text == "["):
+			if p.isOp(".") {
+				p.pos++
+			}
+			if term, err = p.parseBracket(term); err != nil {
+				return nil, err
+			}
+
+		case p.isOp("?"):
+			p.pos++
+			term = tryQuery(term, nil)
+
+		case bind && p.isKeyword("as"):
+			p.pos++
+			if p.peek().kind != qtVar {
+				return nil, p.errorf("expected variable but %s", p.peek().describe())
+			}
+			name := p.next().text
+			if err := p.expectOp("|"); err != nil {
+				return nil, err
+			}
+			body, err := p.parsePipe(false)
+			if err != nil {
+				return nil, err
+			}
+			return bindQuery(term, name, body), nil
+
+		default:
+			return term, nil
+		}
+	}
+}
+
+// parseBracket parses [], [e], [a:b] after a term. Like jq, e, a and b are
+// evaluated against the input of term, not its output.
+
+func (p *queryParser) parseBracket(term queryFunc) (queryFunc, error) {
+	if err := p.expectOp("["); err != nil {
+		return nil, err
+	}
+
+	if p.isOp("]") {
+		p.pos++
+		return pipeQuery(term, iterateQuery), nil
+	}
+
+	var from, to queryFunc
+	var err error
+
+	if !p.isOp(":") {
+		if from, err = p.parsePipe(false); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isOp(":") {
+		p.pos++
+		if !p.isOp("]") {
+			if to, err = p.parsePipe(false); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expectOp("]"); err != nil {
+			return nil, err
+		}
+		return sliceQuery(term, from, to), nil
+	}
+
+	if err := p.expectOp("]"); err != nil {
+		return nil, err
+	}
+
+	return indexQuery(term, from), nil
+}
+
+func (p *queryParser) parsePrimary() (queryFunc, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case qtNum:
+		p.pos++
+		return constQuery(t.num), nil
+
+	case qtStr:
+		return p.parseString()
+
+	case qtField:
+		p.pos++
+		return indexQuery(identityQuery, constQuery(t.text)), nil
+
+	case qtVar:
+		p.pos++
+		return varQuery(t.text), nil
+
+	case qtIdent:
+		switch t.text {
+		case "true":
+			p.pos++
+			return constQuery(true), nil
+		case "false":
+			p.pos++
+			return constQuery(false), nil
+		case "null":
+			p.pos++
+			return constQuery(nil), nil
+		case "if":
+			return p.parseIf()
+		case "try":
+			return p.parseTry()
+		case "reduce":
+			return p.parseReduce()
+		case "then", "elif", "else", "end", "as", "and", "or", "catch":
+			return nil, p.errorf("unexpected %s", t.describe())
+		}
+		return p.parseCall()
+
+	case qtOp:
+		switch t.text {
+		case ".":
+			p.pos++
+			if p.peek().kind == qtStr {
+				key, err := p.parseString()
+				if err != nil {
+					return nil, err
+				}
+				return indexQuery(identityQuery, key), nil
+			}
+			return identityQuery, nil
+
+		case "..":
+			p.pos++
+			return recurseQuery, nil
+
+		case "(":
+			p.pos++
+			f, err := p.parsePipe(false)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return f, nil
+
+		case "[":
+			p.pos++
+			if p.isOp("]") {
+				p.pos++
+				return collectQuery(nil), nil
+			}
+			f, err := p.parsePipe(false)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			return collectQuery(f), nil
+
+		case "{":
+			return p.parseObject()
+		}
+	}
+
+	return nil, p.errorf("unexpected %s", t.describe())
+}
+
+func (p *queryParser) parseString() (queryFunc, error) {
+	t := p.next()
+	if t.kind != qtStr {
+		return nil, queryErrorAt(t.pos, "expected string")
+	}
+
+	if len(t.parts) == 1 && !t.parts[0].isExpr {
+		return constQuery(t.parts[0].lit), nil
+	}
+
+	parts := make([]queryFunc, len(t.parts))
+	for idx, part := range t.parts {
+		if !part.isExpr {
+			parts[idx] = constQuery(part.lit)
+			continue
+		}
+
+		f, err := parseQuery(part.expr)
+		if err != nil {
+			return nil, err
+		}
+		parts[idx] = f
+	}
+
+	return interpolateQuery(parts), nil
+}
+
+func (p *queryParser) parseObject() (queryFunc, error) {
+	if err := p.expectOp("{"); err != nil {
+		return nil, err
+	}
+
+	keys := make([]queryFunc, 0)
+	values := make([]queryFunc, 0)
+
+	for !p.isOp("}") {
+		var key, value queryFunc
+		var err error
+
+		t := p.peek()
+
+		switch {
+		case t.kind == qtVar: // {$x} is {x: $x}
+			p.pos++
+			key, value = constQuery(t.text), varQuery(t.text)
+		case t.kind == qtIdent:
+			p.pos++
+			key = constQuery(t.text)
+		case t.kind == qtStr:
+			if key, err = p.parseString(); err != nil {
+				return nil, err
+			}
+		case p.isOp("("):
+			p.pos++
+			if key, err = p.parsePipe(false); err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, p.errorf("unexpected %s in object", t.describe())
+		}
+
+		if value == nil {
+			if p.isOp(":") {
+				p.pos++
+				if value, err = p.parsePipe(true); err != nil {
+					return nil, err
+				}
+			} else if t.kind == qtIdent || t.kind == qtStr { // {a} is {a: .a}
+				value = indexQuery(identityQuery, key)
+			} else {
+				return nil, p.errorf("expected \":\" but %s", p.peek().describe())
+			}
+		}
+
+		keys = append(keys, key)
+		values = append(values, value)
+
+		if !p.isOp(",") {
+			break
+		}
+		p.pos++
+	}
+
+	if err := p.expectOp("}"); err != nil {
+		return nil, err
+	}
+
+	return objectQuery(keys, values), nil
+}
+
+func (p *queryParser) parseIf() (queryFunc, error) {
+	p.pos++ // if or elif
+
+	cond, err := p.parsePipe(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectKeyword("then"); err != nil {
+		return nil, err
+	}
+
+	then, err := p.parsePipe(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var otherwise queryFunc = identityQuery
+
+	switch {
+	case p.isKeyword("elif"):
+		if otherwise, err = p.parseIf(); err != nil {
+			return nil, err
+		}
+		return ifQuery(cond, then, otherwise), nil
+	case p.isKeyword("else"):
+		p.pos++
+		if otherwise, err = p.parsePipe(false); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expectKeyword("end"); err != nil {
+		return nil, err
+	}
+
+	return ifQuery(cond, then, otherwise), nil
+}
+
+func (p *queryParser) parseTry() (queryFunc, error) {
+	p.pos++ // try
+
+	body, err := p.parsePostfix(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isKeyword("catch") {
+		return tryQuery(body, nil), nil
+	}
+	p.pos++
+
+	handler, err := p.parsePostfix(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return tryQuery(body, handler), nil
+}
+
+// reduce SOURCE as $x (INIT; UPDATE)
+
+func (p *queryParser) parseReduce() (queryFunc, error) {
+	p.pos++ // reduce
+
+	source, err := p.parsePostfix(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectKeyword("as"); err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != qtVar {
+		return nil, p.errorf("expected variable but %s", p.peek().describe())
+	}
+	name := p.next().text
+
+	if err := p.expectOp("("); err != nil {
+		return nil, err
+	}
+
+	init, err := p.parsePipe(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectOp(";"); err != nil {
+		return nil, err
+	}
+
+	update, err := p.parsePipe(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+
+	return reduceQuery(source, name, init, update), nil
+}
+
+func (p *queryParser) parseCall() (queryFunc, error) {
+	t := p.next()
+	args := make([]queryFunc, 0)
+
+	if p.isOp("(") {
+		p.pos++
+		for {
+			arg, err := p.parsePipe(false)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			if p.isOp(";") {
+				p.pos++
+				continue
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	builtin, ok := queryBuiltins[queryBuiltinKey(t.text, len(args))]
+	if !ok {
+		return nil, queryErrorAt(t.pos, fmt.Sprintf("unknown function %s/%d", t.text, len(args)))
+	}
+
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		return builtin(in, vars, args)
+	}, nil
+}
@@ -0,0 +1,870 @@
+package djson
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// queryRunError is an error raised while running a query. value is what
+// `catch` receives, usually the message string.
+
+type queryRunError struct {
+	value interface{}
+}
+
+func (e *queryRunError) Error() string {
+	if s, ok := e.value.(string); ok {
+		return "query error: " + s
+	}
+	return "query error: " + queryToJSON(e.value)
+}
+
+func queryFail(format string, args ...interface{}) error {
+	return &queryRunError{value: fmt.Sprintf(format, args...)}
+}
+
+func constQuery(v interface{}) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		return []interface{}{v}, nil
+	}
+}
+
+func identityQuery(in interface{}, vars *queryVars) ([]interface{}, error) {
+	return []interface{}{in}, nil
+}
+
+func varQuery(name string) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		v, ok := vars.lookup(name)
+		if !ok {
+			return nil, queryFail("$%s is not defined", name)
+		}
+		return []interface{}{v}, nil
+	}
+}
+
+func pipeQuery(left, right queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		louts, err := left(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		outs := make([]interface{}, 0, len(louts))
+		for _, lv := range louts {
+			routs, err := right(lv, vars)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, routs...)
+		}
+
+		return outs, nil
+	}
+}
+
+func commaQuery(left, right queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		louts, err := left(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		routs, err := right(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(louts, routs...), nil
+	}
+}
+
+// altQuery is a // b: the truthy outputs of a, or b if there is none.
+// Errors of a are ignored.
+
+func altQuery(left, right queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		louts, _ := left(in, vars)
+
+		outs := make([]interface{}, 0, len(louts))
+		for _, lv := range louts {
+			if isTruthy(lv) {
+				outs = append(outs, lv)
+			}
+		}
+
+		if len(outs) > 0 {
+			return outs, nil
+		}
+
+		return right(in, vars)
+	}
+}
+
+func logicQuery(left, right queryFunc, isOr bool) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		louts, err := left(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		outs := make([]interface{}, 0, len(louts))
+		for _, lv := range louts {
+			if isTruthy(lv) == isOr {
+				outs = append(outs, isOr)
+				continue
+			}
+
+			routs, err := right(in, vars)
+			if err != nil {
+				return nil, err
+			}
+			for _, rv := range routs {
+				outs = append(outs, isTruthy(rv))
+			}
+		}
+
+		return outs, nil
+	}
+}
+
+// binaryQuery evaluates both sides against the input, right side outer as jq does.
+
+func binaryQuery(left, right queryFunc, op string) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		routs, err := right(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		louts, err := left(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		outs := make([]interface{}, 0, len(louts)*len(routs))
+		for _, rv := range routs {
+			for _, lv := range louts {
+				v, err := queryBinary(op, lv, rv)
+				if err != nil {
+					return nil, err
+				}
+				outs = append(outs, v)
+			}
+		}
+
+		return outs, nil
+	}
+}
+
+func indexQuery(term, key queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		kouts, err := key(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		touts, err := term(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		outs := make([]interface{}, 0, len(touts)*len(kouts))
+		for _, tv := range touts {
+			for _, kv := range kouts {
+				v, err := queryIndex(tv, kv)
+				if err != nil {
+					return nil, err
+				}
+				outs = append(outs, v)
+			}
+		}
+
+		return outs, nil
+	}
+}
+
+func queryIndex(v interface{}, key interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t := v.(type) {
+	case *DO:
+		if k, ok := key.(string); ok {
+			value, _ := t.Get(k)
+			return value, nil
+		}
+	case *DA:
+		if n, ok := queryNumber(key); ok {
+			f, _ := getFloatBase(n)
+			idx := int(math.Floor(f))
+			if idx < 0 {
+				idx += t.Size()
+			}
+			value, _ := t.Get(idx)
+			return value, nil
+		}
+	}
+
+	return nil, queryFail("cannot index %s with %s", queryTypeOf(v), queryTypeOf(key))
+}
+
+func iterateQuery(in interface{}, vars *queryVars) ([]interface{}, error) {
+	switch t := in.(type) {
+	case *DA:
+		outs := make([]interface{}, t.Size())
+		copy(outs, t.Element)
+		return outs, nil
+	case *DO:
+		outs := make([]interface{}, 0, t.Size())
+		for _, k := range t.Keys() {
+			outs = append(outs, t.Map[k])
+		}
+		return outs, nil
+	}
+
+	return nil, queryFail("cannot iterate over %s", queryTypeOf(in))
+}
+
+func sliceQuery(term, from, to queryFunc) queryFunc {
+	bound := func(f queryFunc, in interface{}, vars *queryVars) ([]interface{}, error) {
+		if f == nil {
+			return []interface{}{nil}, nil
+		}
+		return f(in, vars)
+	}
+
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		fouts, err := bound(from, in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		touts, err := bound(to, in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		vouts, err := term(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		outs := make([]interface{}, 0)
+		for _, v := range vouts {
+			for _, tv := range touts {
+				for _, fv := range fouts {
+					sv, err := querySlice(v, fv, tv)
+					if err != nil {
+						return nil, err
+					}
+					outs = append(outs, sv)
+				}
+			}
+		}
+
+		return outs, nil
+	}
+}
+
+func querySlice(v, from, to interface{}) (interface{}, error) {
+	var size int
+
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		size = len([]rune(t))
+	case *DA:
+		size = t.Size()
+	default:
+		return nil, queryFail("cannot slice %s", queryTypeOf(v))
+	}
+
+	clamp := func(b interface{}, def int) (int, error) {
+		if b == nil {
+			return def, nil
+		}
+		n, ok := queryNumber(b)
+		if !ok {
+			return 0, queryFail("slice index must be number")
+		}
+		f, _ := getFloatBase(n)
+		idx := int(math.Floor(f))
+		if idx < 0 {
+			idx += size
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		if idx > size {
+			idx = size
+		}
+		return idx, nil
+	}
+
+	start, err := clamp(from, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := clamp(to, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if end < start {
+		end = start
+	}
+
+	if s, ok := v.(string); ok {
+		return string([]rune(s)[start:end]), nil
+	}
+
+	arr := NewArray()
+	arr.Element = append(arr.Element, v.(*DA).Element[start:end]...)
+	return arr, nil
+}
+
+func recurseQuery(in interface{}, vars *queryVars) ([]interface{}, error) {
+	outs := make([]interface{}, 0)
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		outs = append(outs, v)
+
+		switch t := v.(type) {
+		case *DA:
+			for idx := 0; idx < t.Size(); idx++ {
+				walk(t.Element[idx])
+			}
+		case *DO:
+			for _, k := range t.Keys() {
+				walk(t.Map[k])
+			}
+		}
+	}
+
+	walk(in)
+	return outs, nil
+}
+
+func tryQuery(body, handler queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		outs, err := body(in, vars)
+		if err == nil {
+			return outs, nil
+		}
+
+		if handler == nil {
+			return []interface{}{}, nil
+		}
+
+		var value interface{} = err.Error()
+		if rerr, ok := err.(*queryRunError); ok {
+			value = rerr.value
+		}
+
+		return handler(value, vars)
+	}
+}
+
+func bindQuery(term queryFunc, name string, body queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		touts, err := term(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		outs := make([]interface{}, 0, len(touts))
+		for _, tv := range touts {
+			bouts, err := body(in, &queryVars{name: name, value: tv, parent: vars})
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, bouts...)
+		}
+
+		return outs, nil
+	}
+}
+
+func collectQuery(f queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		arr := NewArray()
+
+		if f != nil {
+			outs, err := f(in, vars)
+			if err != nil {
+				return nil, err
+			}
+			arr.Element = append(arr.Element, outs...)
+		}
+
+		return []interface{}{arr}, nil
+	}
+}
+
+func interpolateQuery(parts []queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		strs := []string{""}
+
+		for _, part := range parts {
+			pouts, err := part(in, vars)
+			if err != nil {
+				return nil, err
+			}
+
+			next := make([]string, 0, len(strs)*len(pouts))
+			for _, pv := range pouts {
+				ps, ok := pv.(string)
+				if !ok {
+					ps = queryToJSON(pv)
+				}
+				for _, s := range strs {
+					next = append(next, s+ps)
+				}
+			}
+			strs = next
+		}
+
+		outs := make([]interface{}, len(strs))
+		for idx := range strs {
+			outs[idx] = strs[idx]
+		}
+		return outs, nil
+	}
+}
+
+// objectQuery builds one object per combination of key and value outputs.
+
+func objectQuery(keys, values []queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		combos := [][]interface{}{{}}
+
+		for idx := range keys {
+			kouts, err := keys[idx](in, vars)
+			if err != nil {
+				return nil, err
+			}
+
+			vouts, err := values[idx](in, vars)
+			if err != nil {
+				return nil, err
+			}
+
+			next := make([][]interface{}, 0, len(combos)*len(kouts)*len(vouts))
+			for _, combo := range combos {
+				for _, kv := range kouts {
+					if _, ok := kv.(string); !ok {
+						return nil, queryFail("object keys must be strings, not %s", queryTypeOf(kv))
+					}
+					for _, vv := range vouts {
+						each := make([]interface{}, len(combo), len(combo)+2)
+						copy(each, combo)
+						next = append(next, append(each, kv, vv))
+					}
+				}
+			}
+			combos = next
+		}
+
+		outs := make([]interface{}, 0, len(combos))
+		for _, combo := range combos {
+			obj := NewObject()
+			for idx := 0; idx < len(combo); idx += 2 {
+				obj.Put(combo[idx].(string), combo[idx+1])
+			}
+			outs = append(outs, obj)
+		}
+
+		return outs, nil
+	}
+}
+
+func ifQuery(cond, then, otherwise queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		couts, err := cond(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		outs := make([]interface{}, 0, len(couts))
+		for _, cv := range couts {
+			branch := otherwise
+			if isTruthy(cv) {
+				branch = then
+			}
+
+			bouts, err := branch(in, vars)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, bouts...)
+		}
+
+		return outs, nil
+	}
+}
+
+func reduceQuery(source queryFunc, name string, init, update queryFunc) queryFunc {
+	return func(in interface{}, vars *queryVars) ([]interface{}, error) {
+		iouts, err := init(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		souts, err := source(in, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		outs := make([]interface{}, 0, len(iouts))
+		for _, acc := range iouts {
+			for _, sv := range souts {
+				uouts, err := update(acc, &queryVars{name: name, value: sv, parent: vars})
+				if err != nil {
+					return nil, err
+				}
+
+				acc = nil
+				if len(uouts) > 0 {
+					acc = uouts[len(uouts)-1]
+				}
+			}
+			outs = append(outs, acc)
+		}
+
+		return outs, nil
+	}
+}
+
+// value helpers
+
+func isTruthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return v != nil
+}
+
+func queryTypeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case *DA:
+		return "array"
+	case *DO:
+		return "object"
+	}
+
+	if _, ok := queryNumber(v); ok {
+		return "number"
+	}
+
+	return "unknown"
+}
+
+// queryNumber returns v as int64 or float64.
+
+func queryNumber(v interface{}) (interface{}, bool) {
+	switch t := v.(type) {
+	case int64:
+		return t, true
+	case float64:
+		return t, true
+	case int, uint, int8, uint8, int16, uint16, int32, uint32, uint64:
+		i, _ := getIntBase(t)
+		return i, true
+	case float32:
+		return float64(t), true
+	}
+	return nil, false
+}
+
+func queryToJSON(v interface{}) string {
+	return marshalString(v, SerializeOptions{})
+}
+
+func queryTypeRank(v interface{}) int {
+	switch queryTypeOf(v) {
+	case "null":
+		return 0
+	case "boolean":
+		if v.(bool) {
+			return 2
+		}
+		return 1
+	case "number":
+		return 3
+	case "string":
+		return 4
+	case "array":
+		return 5
+	case "object":
+		return 6
+	}
+	return 7
+}
+
+// compareValues orders values as jq does:
+// null < false < true < numbers < strings < arrays < objects
+
+func compareValues(a, b interface{}) int {
+	ra, rb := queryTypeRank(a), queryTypeRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch ra {
+	case 3:
+		na, _ := queryNumber(a)
+		nb, _ := queryNumber(b)
+		ia, aInt := na.(int64)
+		ib, bInt := nb.(int64)
+		if aInt && bInt {
+			return compareOrdered(ia < ib, ia > ib)
+		}
+		fa, _ := getFloatBase(na)
+		fb, _ := getFloatBase(nb)
+		return compareOrdered(fa < fb, fa > fb)
+
+	case 4:
+		return strings.Compare(a.(string), b.(string))
+
+	case 5:
+		da, db := a.(*DA), b.(*DA)
+		for idx := 0; idx < da.Size() && idx < db.Size(); idx++ {
+			if c := compareValues(da.Element[idx], db.Element[idx]); c != 0 {
+				return c
+			}
+		}
+		return compareOrdered(da.Size() < db.Size(), da.Size() > db.Size())
+
+	case 6:
+		oa, ob := a.(*DO), b.(*DO)
+		ka, kb := sortedKeys(oa), sortedKeys(ob)
+		for idx := 0; idx < len(ka) && idx < len(kb); idx++ {
+			if c := strings.Compare(ka[idx], kb[idx]); c != 0 {
+				return c
+			}
+		}
+		if len(ka) != len(kb) {
+			return compareOrdered(len(ka) < len(kb), true)
+		}
+		for _, k := range ka {
+			if c := compareValues(oa.Map[k], ob.Map[k]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return 0
+}
+
+func compareOrdered(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(do *DO) []string {
+	keys := do.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+func queryBinary(op string, a, b interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return compareValues(a, b) == 0, nil
+	case "!=":
+		return compareValues(a, b) != 0, nil
+	case "<":
+		return compareValues(a, b) < 0, nil
+	case "<=":
+		return compareValues(a, b) <= 0, nil
+	case ">":
+		return compareValues(a, b) > 0, nil
+	case ">=":
+		return compareValues(a, b) >= 0, nil
+	}
+
+	na, aNum := queryNumber(a)
+	nb, bNum := queryNumber(b)
+
+	if aNum && bNum {
+		return queryArith(op, na, nb)
+	}
+
+	switch op {
+	case "+":
+		if a == nil {
+			return b, nil
+		}
+		if b == nil {
+			return a, nil
+		}
+
+		switch ta := a.(type) {
+		case string:
+			if tb, ok := b.(string); ok {
+				return ta + tb, nil
+			}
+		case *DA:
+			if tb, ok := b.(*DA); ok {
+				ta.load()
+				tb.load()
+				arr := NewArray()
+				arr.Element = append(append(arr.Element, ta.Element...), tb.Element...)
+				return arr, nil
+			}
+		case *DO:
+			if tb, ok := b.(*DO); ok {
+				obj := ta.shallowCopy()
+				for _, k := range tb.Keys() {
+					obj.Put(k, tb.Map[k])
+				}
+				return obj, nil
+			}
+		}
+
+	case "-":
+		if ta, ok := a.(*DA); ok {
+			if tb, ok := b.(*DA); ok {
+				ta.load()
+				tb.load()
+				arr := NewArray()
+				for _, av := range ta.Element {
+					found := false
+					for _, bv := range tb.Element {
+						if compareValues(av, bv) == 0 {
+							found = true
+							break
+						}
+					}
+					if !found {
+						arr.Element = append(arr.Element, av)
+					}
+				}
+				return arr, nil
+			}
+		}
+
+	case "*":
+		if ta, ok := a.(*DO); ok {
+			if tb, ok := b.(*DO); ok {
+				return deepMergeObject(ta, tb), nil
+			}
+		}
+
+	case "/":
+		if ta, ok := a.(string); ok {
+			if tb, ok := b.(string); ok {
+				return splitToArray(ta, tb), nil
+			}
+		}
+	}
+
+	return nil, queryFail("%s and %s cannot be combined with %s", queryTypeOf(a), queryTypeOf(b), op)
+}
+
+func queryArith(op string, a, b interface{}) (interface{}, error) {
+	ia, aInt := a.(int64)
+	ib, bInt := b.(int64)
+
+	// results out of int64 are computed as floats below
+	if aInt && bInt {
+		switch op {
+		case "+":
+			if r := ia + ib; (r > ia) == (ib > 0) {
+				return r, nil
+			}
+		case "-":
+			if r := ia - ib; (r < ia) == (ib > 0) {
+				return r, nil
+			}
+		case "*":
+			if r := ia * ib; ia == 0 || r/ia == ib && !(ia == -1 && ib == math.MinInt64) && !(ib == -1 && ia == math.MinInt64) {
+				return r, nil
+			}
+		case "/":
+			if ib == 0 {
+				return nil, queryFail("division by zero")
+			}
+			if ia%ib == 0 && !(ia == math.MinInt64 && ib == -1) {
+				return ia / ib, nil
+			}
+		case "%":
+			if ib == 0 {
+				return nil, queryFail("modulo by zero")
+			}
+			return ia % ib, nil
+		}
+	}
+
+	fa, _ := getFloatBase(a)
+	fb, _ := getFloatBase(b)
+
+	switch op {
+	case "+":
+		return fa + fb, nil
+	case "-":
+		return fa - fb, nil
+	case "*":
+		return fa * fb, nil
+	case "/":
+		if fb == 0 {
+			return nil, queryFail("division by zero")
+		}
+		return fa / fb, nil
+	case "%":
+		if int64(fb) == 0 {
+			return nil, queryFail("modulo by zero")
+		}
+		return int64(fa) % int64(fb), nil
+	}
+
+	return nil, queryFail("unknown operator %s", op)
+}
+
+func deepMergeObject(a, b *DO) *DO {
+	obj := a.shallowCopy()
+
+	for _, k := range b.Keys() {
+		ao, aok := obj.Map[k].(*DO)
+		bo, bok := b.Map[k].(*DO)
+		if aok && bok {
+			obj.Put(k, deepMergeObject(ao, bo))
+		} else {
+			obj.Put(k, b.Map[k])
+		}
+	}
+
+	return obj
+}
+
+func splitToArray(s, sep string) *DA {
+	arr := NewArray()
+	if s == "" {
+		return arr
+	}
+	for _, each := range strings.Split(s, sep) {
+		arr.Element = append(arr.Element, each)
+	}
+	return arr
+}
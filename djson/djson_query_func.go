@@ -0,0 +1,823 @@
+package djson
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type queryBuiltin func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error)
+
+var queryBuiltins map[string]queryBuiltin
+
+func queryBuiltinKey(name string, arity int) string {
+	return name + "/" + strconv.Itoa(arity)
+}
+
+func init() {
+	queryBuiltins = map[string]queryBuiltin{
+		"empty/0": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			return []interface{}{}, nil
+		},
+		"error/0": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			return nil, &queryRunError{value: in}
+		},
+		"error/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			return eachArg(args[0], in, vars, func(msg interface{}) (interface{}, error) {
+				return nil, &queryRunError{value: msg}
+			})
+		},
+		"not/0":           queryValueFunc(func(in interface{}) (interface{}, error) { return !isTruthy(in), nil }),
+		"length/0":        queryValueFunc(queryLength),
+		"type/0":          queryValueFunc(func(in interface{}) (interface{}, error) { return queryTypeOf(in), nil }),
+		"keys/0":          queryValueFunc(func(in interface{}) (interface{}, error) { return queryKeys(in, true) }),
+		"keys_unsorted/0": queryValueFunc(func(in interface{}) (interface{}, error) { return queryKeys(in, false) }),
+		"tostring/0": queryValueFunc(func(in interface{}) (interface{}, error) {
+			if s, ok := in.(string); ok {
+				return s, nil
+			}
+			return queryToJSON(in), nil
+		}),
+		"tojson/0":   queryValueFunc(func(in interface{}) (interface{}, error) { return queryToJSON(in), nil }),
+		"fromjson/0": queryValueFunc(queryFromJSON),
+		"tonumber/0": queryValueFunc(queryToNumber),
+		"add/0": queryValueFunc(func(in interface{}) (interface{}, error) {
+			values, err := iterateQuery(in, nil)
+			if err != nil {
+				return nil, err
+			}
+			var acc interface{}
+			for _, v := range values {
+				if acc, err = queryBinary("+", acc, v); err != nil {
+					return nil, err
+				}
+			}
+			return acc, nil
+		}),
+		"any/0": queryValueFunc(func(in interface{}) (interface{}, error) { return queryAnyAll(in, nil, nil, true) }),
+		"all/0": queryValueFunc(func(in interface{}) (interface{}, error) { return queryAnyAll(in, nil, nil, false) }),
+		"any/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			v, err := queryAnyAll(in, args[0], vars, true)
+			return []interface{}{v}, err
+		},
+		"all/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			v, err := queryAnyAll(in, args[0], vars, false)
+			return []interface{}{v}, err
+		},
+		"select/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			couts, err := args[0](in, vars)
+			if err != nil {
+				return nil, err
+			}
+			outs := make([]interface{}, 0, 1)
+			for _, cv := range couts {
+				if isTruthy(cv) {
+					outs = append(outs, in)
+				}
+			}
+			return outs, nil
+		},
+		"map/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			return collectQuery(pipeQuery(iterateQuery, args[0]))(in, vars)
+		},
+		"map_values/1":   queryMapValues,
+		"has/1":          queryArgFunc(queryHas),
+		"contains/1":     queryArgFunc(func(in, arg interface{}) (interface{}, error) { return queryContains(in, arg) }),
+		"to_entries/0":   queryValueFunc(queryToEntries),
+		"from_entries/0": queryValueFunc(queryFromEntries),
+		"with_entries/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			entries, err := queryToEntries(in)
+			if err != nil {
+				return nil, err
+			}
+			mapped, err := collectQuery(pipeQuery(iterateQuery, args[0]))(entries, vars)
+			if err != nil {
+				return nil, err
+			}
+			obj, err := queryFromEntries(mapped[0])
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{obj}, nil
+		},
+		"sort/0": queryValueFunc(func(in interface{}) (interface{}, error) { return querySortBy(in, nil, nil) }),
+		"sort_by/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			v, err := querySortBy(in, args[0], vars)
+			return []interface{}{v}, err
+		},
+		"group_by/1":  queryGroupFunc(func(groups []*DA) interface{} { return queryGroups(groups, false) }),
+		"unique/0":    queryValueFunc(func(in interface{}) (interface{}, error) { return queryGroupBy(in, nil, nil, true) }),
+		"unique_by/1": queryGroupFunc(func(groups []*DA) interface{} { return queryGroups(groups, true) }),
+		"min/0":       queryValueFunc(func(in interface{}) (interface{}, error) { return queryExtreme(in, nil, nil, false) }),
+		"max/0":       queryValueFunc(func(in interface{}) (interface{}, error) { return queryExtreme(in, nil, nil, true) }),
+		"min_by/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			v, err := queryExtreme(in, args[0], vars, false)
+			return []interface{}{v}, err
+		},
+		"max_by/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			v, err := queryExtreme(in, args[0], vars, true)
+			return []interface{}{v}, err
+		},
+		"reverse/0": queryValueFunc(queryReverse),
+		"first/0":   queryValueFunc(func(in interface{}) (interface{}, error) { return queryIndex(in, int64(0)) }),
+		"last/0":    queryValueFunc(func(in interface{}) (interface{}, error) { return queryIndex(in, int64(-1)) }),
+		"first/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			outs, err := args[0](in, vars)
+			if err != nil || len(outs) == 0 {
+				return outs, err
+			}
+			return outs[:1], nil
+		},
+		"last/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			outs, err := args[0](in, vars)
+			if err != nil || len(outs) == 0 {
+				return outs, err
+			}
+			return outs[len(outs)-1:], nil
+		},
+		"limit/2": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			nouts, err := args[0](in, vars)
+			if err != nil {
+				return nil, err
+			}
+			outs, err := args[1](in, vars)
+			if err != nil {
+				return nil, err
+			}
+			rets := make([]interface{}, 0)
+			for _, nv := range nouts {
+				n, _ := getIntBase(nv)
+				if n > int64(len(outs)) {
+					n = int64(len(outs))
+				}
+				if n > 0 {
+					rets = append(rets, outs[:n]...)
+				}
+			}
+			return rets, nil
+		},
+		"range/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			return queryRange(in, vars, constQuery(int64(0)), args[0])
+		},
+		"range/2": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			return queryRange(in, vars, args[0], args[1])
+		},
+		"recurse/0": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			return recurseQuery(in, vars)
+		},
+		"recurse/1": func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+			outs := make([]interface{}, 0)
+			var walk func(v interface{}) error
+			walk = func(v interface{}) error {
+				outs = append(outs, v)
+				nexts, err := args[0](v, vars)
+				if err != nil {
+					return err
+				}
+				for _, next := range nexts {
+					if err := walk(next); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			if err := walk(in); err != nil {
+				return nil, err
+			}
+			return outs, nil
+		},
+		"getpath/1": queryArgFunc(func(in, path interface{}) (interface{}, error) {
+			arr, ok := path.(*DA)
+			if !ok {
+				return nil, queryFail("path must be array")
+			}
+			v := in
+			for idx := 0; idx < arr.Size(); idx++ {
+				var err error
+				if v, err = queryIndex(v, arr.Element[idx]); err != nil {
+					return nil, err
+				}
+			}
+			return v, nil
+		}),
+		"flatten/0": queryValueFunc(func(in interface{}) (interface{}, error) { return queryFlatten(in, -1) }),
+		"flatten/1": queryArgFunc(func(in, depth interface{}) (interface{}, error) {
+			d, ok := queryNumber(depth)
+			if !ok {
+				return nil, queryFail("flatten depth must be number")
+			}
+			n, _ := getIntBase(d)
+			if n < 0 {
+				return nil, queryFail("flatten depth must not be negative")
+			}
+			return queryFlatten(in, int(n))
+		}),
+		"join/1":           queryArgFunc(queryJoin),
+		"split/1":          queryStringFunc(func(s, arg string) interface{} { return splitToArray(s, arg) }),
+		"startswith/1":     queryStringFunc(func(s, arg string) interface{} { return strings.HasPrefix(s, arg) }),
+		"endswith/1":       queryStringFunc(func(s, arg string) interface{} { return strings.HasSuffix(s, arg) }),
+		"ltrimstr/1":       queryStringFunc(func(s, arg string) interface{} { return strings.TrimPrefix(s, arg) }),
+		"rtrimstr/1":       queryStringFunc(func(s, arg string) interface{} { return strings.TrimSuffix(s, arg) }),
+		"ascii_downcase/0": queryValueFunc(func(in interface{}) (interface{}, error) { return queryASCIICase(in, false) }),
+		"ascii_upcase/0":   queryValueFunc(func(in interface{}) (interface{}, error) { return queryASCIICase(in, true) }),
+		"test/1": queryArgFunc(func(in, re interface{}) (interface{}, error) {
+			s, ok := in.(string)
+			pattern, pok := re.(string)
+			if !ok || !pok {
+				return nil, queryFail("test requires string input and pattern")
+			}
+			r, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, queryFail("%s", err.Error())
+			}
+			return r.MatchString(s), nil
+		}),
+		"floor/0": queryMathFunc(math.Floor),
+		"ceil/0":  queryMathFunc(math.Ceil),
+		"round/0": queryMathFunc(math.Round),
+		"sqrt/0":  queryMathFunc(math.Sqrt),
+		"fabs/0":  queryMathFunc(math.Abs),
+	}
+
+	for _, name := range []string{"nulls", "booleans", "numbers", "strings", "arrays", "objects", "iterables", "scalars"} {
+		queryBuiltins[name+"/0"] = queryTypeFilter(name)
+	}
+}
+
+// queryValueFunc adapts a one output builtin without arguments.
+
+func queryValueFunc(f func(in interface{}) (interface{}, error)) queryBuiltin {
+	return func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+		v, err := f(in)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{v}, nil
+	}
+}
+
+// queryArgFunc adapts a builtin with one value argument, called for each output of it.
+
+func queryArgFunc(f func(in, arg interface{}) (interface{}, error)) queryBuiltin {
+	return func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+		return eachArg(args[0], in, vars, func(arg interface{}) (interface{}, error) {
+			return f(in, arg)
+		})
+	}
+}
+
+func queryStringFunc(f func(s, arg string) interface{}) queryBuiltin {
+	return queryArgFunc(func(in, arg interface{}) (interface{}, error) {
+		s, ok := in.(string)
+		a, aok := arg.(string)
+		if !ok || !aok {
+			return nil, queryFail("%s and %s cannot be used as strings", queryTypeOf(in), queryTypeOf(arg))
+		}
+		return f(s, a), nil
+	})
+}
+
+func queryMathFunc(f func(float64) float64) queryBuiltin {
+	return queryValueFunc(func(in interface{}) (interface{}, error) {
+		n, ok := queryNumber(in)
+		if !ok {
+			return nil, queryFail("%s is not a number", queryTypeOf(in))
+		}
+		fv, _ := getFloatBase(n)
+		r := f(fv)
+		if r == math.Trunc(r) && math.Abs(r) < 1<<53 {
+			return int64(r), nil
+		}
+		return r, nil
+	})
+}
+
+func queryTypeFilter(name string) queryBuiltin {
+	return func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+		t := queryTypeOf(in)
+
+		ok := t+"s" == name
+		switch name {
+		case "iterables":
+			ok = t == "array" || t == "object"
+		case "scalars":
+			ok = t != "array" && t != "object"
+		}
+
+		if ok {
+			return []interface{}{in}, nil
+		}
+		return []interface{}{}, nil
+	}
+}
+
+func eachArg(arg queryFunc, in interface{}, vars *queryVars, f func(arg interface{}) (interface{}, error)) ([]interface{}, error) {
+	aouts, err := arg(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	outs := make([]interface{}, 0, len(aouts))
+	for _, av := range aouts {
+		v, err := f(av)
+		if err != nil {
+			return nil, err
+		}
+		outs = append(outs, v)
+	}
+
+	return outs, nil
+}
+
+func queryLength(in interface{}) (interface{}, error) {
+	switch t := in.(type) {
+	case nil:
+		return int64(0), nil
+	case string:
+		return int64(utf8.RuneCountInString(t)), nil
+	case *DA:
+		return int64(t.Size()), nil
+	case *DO:
+		return int64(t.Size()), nil
+	case bool:
+		return nil, queryFail("boolean has no length")
+	}
+
+	n, ok := queryNumber(in)
+	if !ok {
+		return nil, queryFail("%s has no length", queryTypeOf(in))
+	}
+
+	if i, ok := n.(int64); ok {
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}
+
+	return math.Abs(n.(float64)), nil
+}
+
+func queryKeys(in interface{}, sorted bool) (interface{}, error) {
+	arr := NewArray()
+
+	switch t := in.(type) {
+	case *DO:
+		keys := t.Keys()
+		if sorted {
+			sort.Strings(keys)
+		}
+		for _, k := range keys {
+			arr.Element = append(arr.Element, k)
+		}
+	case *DA:
+		for idx := 0; idx < t.Size(); idx++ {
+			arr.Element = append(arr.Element, int64(idx))
+		}
+	default:
+		return nil, queryFail("%s has no keys", queryTypeOf(in))
+	}
+
+	return arr, nil
+}
+
+func queryHas(in, key interface{}) (interface{}, error) {
+	switch t := in.(type) {
+	case *DO:
+		if k, ok := key.(string); ok {
+			return t.HasKey(k), nil
+		}
+	case *DA:
+		if n, ok := queryNumber(key); ok {
+			idx, _ := getIntBase(n)
+			return idx >= 0 && idx < int64(t.Size()), nil
+		}
+	}
+
+	return nil, queryFail("cannot check whether %s has a %s key", queryTypeOf(in), queryTypeOf(key))
+}
+
+func queryContains(a, b interface{}) (bool, error) {
+	if queryTypeOf(a) != queryTypeOf(b) {
+		return false, queryFail("%s and %s cannot have their containment checked", queryTypeOf(a), queryTypeOf(b))
+	}
+
+	switch ta := a.(type) {
+	case string:
+		return strings.Contains(ta, b.(string)), nil
+	case *DA:
+		tb := b.(*DA)
+		for bdx := 0; bdx < tb.Size(); bdx++ {
+			found := false
+			for adx := 0; adx < ta.Size() && !found; adx++ {
+				if queryTypeOf(ta.Element[adx]) == queryTypeOf(tb.Element[bdx]) {
+					found, _ = queryContains(ta.Element[adx], tb.Element[bdx])
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	case *DO:
+		tb := b.(*DO)
+		for _, k := range tb.Keys() {
+			av, ok := ta.Get(k)
+			if !ok || queryTypeOf(av) != queryTypeOf(tb.Map[k]) {
+				return false, nil
+			}
+			if c, _ := queryContains(av, tb.Map[k]); !c {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	return compareValues(a, b) == 0, nil
+}
+
+func queryMapValues(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+	switch t := in.(type) {
+	case *DA:
+		arr := NewArray()
+		for idx := 0; idx < t.Size(); idx++ {
+			outs, err := args[0](t.Element[idx], vars)
+			if err != nil {
+				return nil, err
+			}
+			if len(outs) > 0 {
+				arr.Element = append(arr.Element, outs[0])
+			}
+		}
+		return []interface{}{arr}, nil
+	case *DO:
+		obj := NewObject()
+		for _, k := range t.Keys() {
+			outs, err := args[0](t.Map[k], vars)
+			if err != nil {
+				return nil, err
+			}
+			if len(outs) > 0 {
+				obj.Put(k, outs[0])
+			}
+		}
+		return []interface{}{obj}, nil
+	}
+
+	return nil, queryFail("cannot iterate over %s", queryTypeOf(in))
+}
+
+func queryToEntries(in interface{}) (interface{}, error) {
+	t, ok := in.(*DO)
+	if !ok {
+		return nil, queryFail("%s has no entries", queryTypeOf(in))
+	}
+
+	arr := NewArray()
+	for _, k := range t.Keys() {
+		arr.Element = append(arr.Element, NewObject().Put("key", k).Put("value", t.Map[k]))
+	}
+
+	return arr, nil
+}
+
+func queryFromEntries(in interface{}) (interface{}, error) {
+	values, err := iterateQuery(in, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := NewObject()
+
+	for _, v := range values {
+		entry, ok := v.(*DO)
+		if !ok {
+			return nil, queryFail("entry must be object, not %s", queryTypeOf(v))
+		}
+
+		var key interface{}
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if key, ok = entry.Get(name); ok && key != nil {
+				break
+			}
+		}
+
+		var value interface{}
+		for _, name := range []string{"value", "v", "Value", "V"} {
+			if value, ok = entry.Get(name); ok {
+				break
+			}
+		}
+
+		switch tk := key.(type) {
+		case string:
+			obj.Put(tk, value)
+		case bool:
+			obj.Put(strconv.FormatBool(tk), value)
+		default:
+			if _, ok := queryNumber(key); !ok {
+				return nil, queryFail("entry key must be string, not %s", queryTypeOf(key))
+			}
+			obj.Put(queryToJSON(key), value)
+		}
+	}
+
+	return obj, nil
+}
+
+func queryFromJSON(in interface{}) (interface{}, error) {
+	s, ok := in.(string)
+	if !ok {
+		return nil, queryFail("%s cannot be parsed as JSON", queryTypeOf(in))
+	}
+
+	v, err := decodeDocument([]byte(s), &ParseOptions{})
+	if err != nil {
+		return nil, queryFail("%s", err.Error())
+	}
+
+	return v, nil
+}
+
+func queryToNumber(in interface{}) (interface{}, error) {
+	if n, ok := queryNumber(in); ok {
+		return n, nil
+	}
+
+	if s, ok := in.(string); ok {
+		s = strings.TrimSpace(s)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f, nil
+		}
+	}
+
+	return nil, queryFail("%s cannot be parsed as a number", queryToJSON(in))
+}
+
+func queryAnyAll(in interface{}, f queryFunc, vars *queryVars, isAny bool) (interface{}, error) {
+	values, err := iterateQuery(in, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range values {
+		cond := isTruthy(v)
+
+		if f != nil {
+			outs, err := f(v, vars)
+			if err != nil {
+				return nil, err
+			}
+			cond = false
+			for _, o := range outs {
+				if isTruthy(o) {
+					cond = true
+					break
+				}
+			}
+		}
+
+		if cond == isAny {
+			return isAny, nil
+		}
+	}
+
+	return !isAny, nil
+}
+
+// queryKeyed pairs each array element with the outputs of f as its sort key.
+
+type queryKeyed struct {
+	key   *DA
+	value interface{}
+}
+
+func queryKeyedValues(in interface{}, f queryFunc, vars *queryVars) ([]queryKeyed, error) {
+	arr, ok := in.(*DA)
+	if !ok {
+		return nil, queryFail("%s cannot be sorted, as it is not an array", queryTypeOf(in))
+	}
+
+	keyed := make([]queryKeyed, arr.Size())
+
+	for idx := range keyed {
+		keyed[idx].value = arr.Element[idx]
+		keyed[idx].key = NewArray()
+
+		if f == nil {
+			keyed[idx].key.Element = append(keyed[idx].key.Element, arr.Element[idx])
+			continue
+		}
+
+		outs, err := f(arr.Element[idx], vars)
+		if err != nil {
+			return nil, err
+		}
+		keyed[idx].key.Element = append(keyed[idx].key.Element, outs...)
+	}
+
+	sort.SliceStable(keyed, func(i, j int) bool {
+		return compareValues(keyed[i].key, keyed[j].key) < 0
+	})
+
+	return keyed, nil
+}
+
+func querySortBy(in interface{}, f queryFunc, vars *queryVars) (interface{}, error) {
+	keyed, err := queryKeyedValues(in, f, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	arr := NewArray()
+	for idx := range keyed {
+		arr.Element = append(arr.Element, keyed[idx].value)
+	}
+
+	return arr, nil
+}
+
+func queryGroupFunc(f func(groups []*DA) interface{}) queryBuiltin {
+	return func(in interface{}, vars *queryVars, args []queryFunc) ([]interface{}, error) {
+		groups, err := queryGroupValues(in, args[0], vars)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{f(groups)}, nil
+	}
+}
+
+func queryGroupBy(in interface{}, f queryFunc, vars *queryVars, firstOnly bool) (interface{}, error) {
+	groups, err := queryGroupValues(in, f, vars)
+	if err != nil {
+		return nil, err
+	}
+	return queryGroups(groups, firstOnly), nil
+}
+
+func queryGroupValues(in interface{}, f queryFunc, vars *queryVars) ([]*DA, error) {
+	keyed, err := queryKeyedValues(in, f, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*DA, 0)
+	for idx := range keyed {
+		if idx == 0 || compareValues(keyed[idx-1].key, keyed[idx].key) != 0 {
+			groups = append(groups, NewArray())
+		}
+		group := groups[len(groups)-1]
+		group.Element = append(group.Element, keyed[idx].value)
+	}
+
+	return groups, nil
+}
+
+// queryGroups returns the groups, or only the first of each group with firstOnly.
+
+func queryGroups(groups []*DA, firstOnly bool) interface{} {
+	arr := NewArray()
+	for _, group := range groups {
+		if firstOnly {
+			arr.Element = append(arr.Element, group.Element[0])
+		} else {
+			arr.Element = append(arr.Element, group)
+		}
+	}
+	return arr
+}
+
+func queryExtreme(in interface{}, f queryFunc, vars *queryVars, isMax bool) (interface{}, error) {
+	keyed, err := queryKeyedValues(in, f, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keyed) == 0 {
+		return nil, nil
+	}
+
+	if isMax {
+		return keyed[len(keyed)-1].value, nil
+	}
+
+	return keyed[0].value, nil
+}
+
+func queryReverse(in interface{}) (interface{}, error) {
+	switch t := in.(type) {
+	case nil:
+		return NewArray(), nil
+	case string:
+		runes := []rune(t)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	case *DA:
+		arr := NewArray()
+		for idx := t.Size() - 1; idx >= 0; idx-- {
+			arr.Element = append(arr.Element, t.Element[idx])
+		}
+		return arr, nil
+	}
+
+	return nil, queryFail("cannot reverse %s", queryTypeOf(in))
+}
+
+func queryRange(in interface{}, vars *queryVars, from, upto queryFunc) ([]interface{}, error) {
+	fouts, err := from(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	uouts, err := upto(in, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	outs := make([]interface{}, 0)
+	for _, fv := range fouts {
+		for _, uv := range uouts {
+			f, fok := queryNumber(fv)
+			u, uok := queryNumber(uv)
+			if !fok || !uok {
+				return nil, queryFail("range bounds must be numbers")
+			}
+
+			fi, _ := getIntBase(f)
+			ui, _ := getIntBase(u)
+			for idx := fi; idx < ui; idx++ {
+				outs = append(outs, idx)
+			}
+		}
+	}
+
+	return outs, nil
+}
+
+func queryFlatten(in interface{}, depth int) (interface{}, error) {
+	t, ok := in.(*DA)
+	if !ok {
+		return nil, queryFail("cannot flatten %s", queryTypeOf(in))
+	}
+
+	arr := NewArray()
+	for idx := 0; idx < t.Size(); idx++ {
+		if sub, ok := t.Element[idx].(*DA); ok && depth != 0 {
+			flat, _ := queryFlatten(sub, depth-1)
+			arr.Element = append(arr.Element, flat.(*DA).Element...)
+			continue
+		}
+		arr.Element = append(arr.Element, t.Element[idx])
+	}
+
+	return arr, nil
+}
+
+func queryJoin(in, sep interface{}) (interface{}, error) {
+	t, ok := in.(*DA)
+	s, sok := sep.(string)
+	if !ok || !sok {
+		return nil, queryFail("cannot join %s with %s", queryTypeOf(in), queryTypeOf(sep))
+	}
+
+	strs := make([]string, 0, t.Size())
+	for idx := 0; idx < t.Size(); idx++ {
+		switch v := t.Element[idx].(type) {
+		case nil:
+			strs = append(strs, "")
+		case string:
+			strs = append(strs, v)
+		case *DO, *DA:
+			return nil, queryFail("cannot join with %s", queryTypeOf(v))
+		default:
+			strs = append(strs, queryToJSON(v))
+		}
+	}
+
+	return strings.Join(strs, s), nil
+}
+
+func queryASCIICase(in interface{}, upper bool) (interface{}, error) {
+	s, ok := in.(string)
+	if !ok {
+		return nil, queryFail("%s cannot be case converted", queryTypeOf(in))
+	}
+
+	return strings.Map(func(r rune) rune {
+		if upper && r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if !upper && r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+		return r
+	}, s), nil
+}
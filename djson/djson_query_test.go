@@ -0,0 +1,142 @@
+package djson
+
+import (
+	"log"
+	"strings"
+	"testing"
+)
+
+var queryTestDoc = `{
+	"order": {"id": "A-1", "total": 30.5, "currency": "KRW"},
+	"customer": {"first": "Gil-dong", "last": "Hong", "vip": true},
+	"items": [
+		{"sku": "x1", "qty": 2, "price": 10},
+		{"sku": "y2", "qty": 1, "price": 10.5},
+		{"sku": "z3", "qty": 0, "price": 3}
+	]
+}`
+
+func TestQuery(t *testing.T) {
+	aJson := NewDJSON().Parse(queryTestDoc)
+
+	cases := map[string]string{
+		`.order.id`:                            `"A-1"`,
+		`.items[1].sku`:                        `"y2"`,
+		`.items[-1].sku`:                       `"z3"`,
+		`.items[1:].[0].sku`:                   `"y2"`,
+		`[.items[] | .sku]`:                    `["x1","y2","z3"]`,
+		`.items | length`:                      `3`,
+		`.customer | keys`:                     `["first","last","vip"]`,
+		`[.items[] | select(.qty > 0) | .sku]`: `["x1","y2"]`,
+		`.items | map(.qty * .price) | add`:    `30.5`,
+		`{id: .order.id, name: "\(.customer.last) \(.customer.first)"}`:                       `{"id":"A-1","name":"Hong Gil-dong"}`,
+		`if .customer.vip then "gold" elif .order.total > 10 then "silver" else "bronze" end`: `"gold"`,
+		`.missing // "none"`:                                    `"none"`,
+		`.items | map(select(.qty == 0)) | length == 1`:         `true`,
+		`has("order") and (.order | has("nope") | not)`:         `true`,
+		`.items | sort_by(.price) | map(.sku)`:                  `["z3","x1","y2"]`,
+		`.items | group_by(.price) | length`:                    `3`,
+		`[.items[].qty] | unique`:                               `[0,1,2]`,
+		`.order | to_entries | map(.key) | join(",")`:           `"id,total,currency"`,
+		`.order | with_entries(select(.key != "total"))`:        `{"currency":"KRW","id":"A-1"}`,
+		`.items[0] as $first | .items | map(.qty - $first.qty)`: `[0,-1,-2]`,
+		`reduce .items[] as $i (0; . + $i.qty)`:                 `3`,
+		`[.. | numbers] | length`:                               `7`,
+		`.order.total | tostring | tonumber | floor`:            `30`,
+		`"a,b" | split(",")`:                                    `["a","b"]`,
+		`try error("bad") catch .`:                              `"bad"`,
+		`[range(3)] | map(. * 2) | reverse`:                     `[4,2,0]`,
+		`{(.order.currency): .order.total}`:                     `{"KRW":30.5}`,
+		`[.items[] | {sku}]`:                                    `[{"sku":"x1"},{"sku":"y2"},{"sku":"z3"}]`,
+		`.customer * {"vip": false, "tier": 1}`:                 `{"first":"Gil-dong","last":"Hong","tier":1,"vip":false}`,
+		`.items | first(.[] | select(.price > 5)) | .sku`:       `"x1"`,
+		`"A-1" | test("^[A-Z]-[0-9]+$")`:                        `true`,
+		`[.items[].price] | min, max`:                           `3`,
+		`.items | any(.qty == 0), all(.qty > 0)`:                `true`,
+		`[limit(2; .items[])] | length`:                         `2`,
+		`"abc" | ascii_upcase | .[1:]`:                          `"BC"`,
+		`[1, [2, [3]]] | flatten`:                               `[1,2,3]`,
+		`.order | del_unknown? // "no"`:                         ``,
+		`9223372036854775807 + 1 | . > 0`:                       `true`,
+		`9223372036854775807 * 2 > 0`:                           `true`,
+		`-9223372036854775807 - 2 < 0`:                          `true`,
+		`4611686018427387904 * 2 | . > 0`:                       `true`,
+		`3037000499 * 3037000499`:                               `9223372030926249001`,
+	}
+
+	for expr, expected := range cases {
+		q, err := CompileQuery(expr)
+		if expected == "" {
+			if err == nil {
+				log.Fatal("must not compile: ", expr)
+			}
+			continue
+		}
+
+		if err != nil {
+			log.Fatal(expr, ": ", err)
+		}
+
+		ret, err := q.RunOne(aJson)
+		if err != nil {
+			log.Fatal(expr, ": ", err)
+		}
+
+		if out := ret.ToStringWith(SerializeOptions{SortKeys: true}); out != expected {
+			log.Fatal(expr, ": expected ", expected, " but ", out)
+		}
+	}
+
+	rets, _ := aJson.Query(`.items[] | .sku`)
+	if len(rets) != 3 || rets[2].ToString() != "z3" {
+		log.Fatal("must return 3 results")
+	}
+
+	// results do not share values with the input
+	ret, _ := MustCompileQuery(`.order`).RunOne(aJson)
+	ret.Put("id", "B-2")
+	if aJson.GetAsStringPath(`["order"]["id"]`) != "A-1" {
+		log.Fatal("input must not be changed")
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	aJson := NewDJSON().Parse(queryTestDoc)
+
+	for _, expr := range []string{`.items[`, `{a:}`, `if . then 1`, `.a | nofunc`, `"abc`, `.[] as x | .`} {
+		if _, err := CompileQuery(expr); err == nil {
+			log.Fatal("must not compile: ", expr)
+		} else {
+			log.Println(err)
+		}
+	}
+
+	for _, expr := range []string{`.order.id[0]`, `.items.sku`, `.order.id + 1`, `$nope`, `error({"code": 1})`} {
+		if _, err := MustCompileQuery(expr).Run(aJson); err == nil {
+			log.Fatal("must fail: ", expr)
+		} else {
+			log.Println(err)
+		}
+	}
+
+	if rets, err := MustCompileQuery(`.items.sku?`).Run(aJson); err != nil || len(rets) != 0 {
+		log.Fatal("? must suppress errors")
+	}
+
+	// a mapping stored as data, compiled once
+	mapping := `
+	# partner A payload
+	{
+		orderNo: .order.id,
+		amount:  .order.total,
+		lines:   [.items[] | select(.qty > 0) | {code: .sku, count: .qty}]
+	}`
+
+	q := MustCompileQuery(mapping)
+	for idx := 0; idx < 2; idx++ {
+		ret, err := q.RunOne(aJson)
+		if err != nil || !strings.Contains(ret.ToString(), `"lines":[{"code":"x1","count":2},{"code":"y2","count":1}]`) {
+			log.Fatal("mapping failed: ", ret.ToString(), err)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package djson
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	REDACT_REMOVE  = "remove"
+	REDACT_REPLACE = "replace"
+	REDACT_HASH    = "hash"
+	REDACT_MASK    = "mask"
+)
+
+const defaultReplacement = "***"
+
+// RedactRule selects values by exactly one of Path (e.g. ["user"]["ssn"]),
+// Pattern (JSONPath-style, e.g. $..password or $.cards[*].number) or
+// Key (regular expression on object key names).
+
+type RedactRule struct {
+	Path    string
+	Pattern string
+	Key     string
+	Action  string
+
+	pathToken []interface{}
+	steps     []patternStep
+	keyRegExp *regexp.Regexp
+}
+
+type RedactPolicy struct {
+	Salt        string
+	Replacement string
+	Rules       []*RedactRule
+}
+
+type patternStep struct {
+	recursive bool
+	wildcard  bool
+	isIndex   bool
+	index     int
+	key       string
+}
+
+func NewRedactPolicy(salt ...string) *RedactPolicy {
+	m := &RedactPolicy{
+		Replacement: defaultReplacement,
+		Rules:       make([]*RedactRule, 0),
+	}
+
+	if len(salt) > 0 {
+		m.Salt = salt[0]
+	}
+
+	return m
+}
+
+func (m *RedactPolicy) AddPath(path string, action string) error {
+	return m.AddRule(&RedactRule{Path: path, Action: action})
+}
+
+func (m *RedactPolicy) AddPattern(pattern string, action string) error {
+	return m.AddRule(&RedactRule{Pattern: pattern, Action: action})
+}
+
+func (m *RedactPolicy) AddKey(keyRegExp string, action string) error {
+	return m.AddRule(&RedactRule{Key: keyRegExp, Action: action})
+}
+
+func (m *RedactPolicy) AddRule(rule *RedactRule) error {
+	switch rule.Action {
+	case REDACT_REMOVE, REDACT_REPLACE, REDACT_HASH, REDACT_MASK:
+	default:
+		return fmt.Errorf("%w: %s", invalidRedactActionError, rule.Action)
+	}
+
+	var err error
+
+	switch {
+	case rule.Path != "":
+		rule.pathToken = PathTokenizer(rule.Path)
+		if len(rule.pathToken) == 0 {
+			return invalidPathError
+		}
+	case rule.Pattern != "":
+		if rule.steps, err = parsePattern(rule.Pattern); err != nil {
+			return err
+		}
+	case rule.Key != "":
+		if rule.keyRegExp, err = regexp.Compile(rule.Key); err != nil {
+			return err
+		}
+	default:
+		return invalidRedactRuleError
+	}
+
+	m.Rules = append(m.Rules, rule)
+	return nil
+}
+
+// ParseRedactPolicy reads a policy like
+// {"salt":"s3cr3t","replacement":"***","rules":[{"key":"(?i)password","action":"remove"},{"pattern":"$..card","action":"mask"}]}
+
+func ParseRedactPolicy(doc string) (*RedactPolicy, error) {
+	pjson := NewDJSON().Parse(doc)
+	if !pjson.IsObject() {
+		return nil, invalidRedactRuleError
+	}
+
+	m := NewRedactPolicy(pjson.GetAsString("salt"))
+	m.Replacement = pjson.GetAsString("replacement", defaultReplacement)
+
+	rules, ok := pjson.GetAsArray("rules")
+	if !ok {
+		return m, nil
+	}
+
+	for idx := 0; idx < rules.Length(); idx++ {
+		each, ok := rules.GetAsObject(idx)
+		if !ok {
+			return nil, invalidRedactRuleError
+		}
+
+		err := m.AddRule(&RedactRule{
+			Path:    each.GetAsString("path", ""),
+			Pattern: each.GetAsString("pattern", ""),
+			Key:     each.GetAsString("key", ""),
+			Action:  each.GetAsString("action", ""),
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func LoadRedactPolicy(filename string) (*RedactPolicy, error) {
+	doc, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRedactPolicy(string(doc))
+}
+
+// Redact returns a redacted copy of the document. The original is left untouched.
+
+func (m *DJSON) Redact(policy *RedactPolicy) *DJSON {
+	t := m.Clone()
+
+	if policy == nil || len(policy.Rules) == 0 {
+		return t
+	}
+
+	switch t.JsonType {
+	case JSON_OBJECT:
+		policy.redactObject(t.Object, []interface{}{})
+	case JSON_ARRAY:
+		policy.redactArray(t.Array, []interface{}{})
+	}
+
+	return t
+}
+
+func (m *RedactPolicy) redactObject(do *DO, token []interface{}) {
+	for _, key := range do.Keys() {
+		eachToken := appendToken(token, key)
+
+		if rule := m.findRule(eachToken); rule != nil {
+			if rule.Action == REDACT_REMOVE {
+				do.Remove(key)
+			} else {
+				do.Put(key, m.redactValue(rule, do.GetAsString(key)))
+			}
+			continue
+		}
+
+		switch t := do.Map[key].(type) {
+		case *DO:
+			m.redactObject(t, eachToken)
+		case *DA:
+			m.redactArray(t, eachToken)
+		}
+	}
+}
+
+func (m *RedactPolicy) redactArray(da *DA, token []interface{}) {
+	removed := make([]int, 0)
+
+	for idx := 0; idx < da.Size(); idx++ {
+		eachToken := appendToken(token, idx)
+
+		if rule := m.findRule(eachToken); rule != nil {
+			if rule.Action == REDACT_REMOVE {
+				removed = append(removed, idx)
+			} else {
+				da.ReplaceAt(idx, m.redactValue(rule, da.GetAsString(idx)))
+			}
+			continue
+		}
+
+		switch t := da.Element[idx].(type) {
+		case *DO:
+			m.redactObject(t, eachToken)
+		case *DA:
+			m.redactArray(t, eachToken)
+		}
+	}
+
+	for idx := len(removed) - 1; idx >= 0; idx-- {
+		da.Remove(removed[idx])
+	}
+}
+
+func (m *RedactPolicy) redactValue(rule *RedactRule, val string) string {
+	switch rule.Action {
+	case REDACT_HASH:
+		mac := hmac.New(sha256.New, []byte(m.Salt))
+		mac.Write([]byte(val))
+		return hex.EncodeToString(mac.Sum(nil))
+	case REDACT_MASK:
+		runes := []rune(val)
+		keep := 4
+		if len(runes) <= keep {
+			keep = 0
+		}
+		for idx := 0; idx < len(runes)-keep; idx++ {
+			runes[idx] = '*'
+		}
+		return string(runes)
+	}
+
+	if m.Replacement == "" {
+		return defaultReplacement
+	}
+
+	return m.Replacement
+}
+
+func (m *RedactPolicy) findRule(token []interface{}) *RedactRule {
+	for _, rule := range m.Rules {
+		switch {
+		case rule.pathToken != nil:
+			if isSamePath(rule.pathToken, token) {
+				return rule
+			}
+		case rule.steps != nil:
+			if matchPattern(rule.steps, token) {
+				return rule
+			}
+		case rule.keyRegExp != nil:
+			if key, ok := token[len(token)-1].(string); ok && rule.keyRegExp.MatchString(key) {
+				return rule
+			}
+		}
+	}
+
+	return nil
+}
+
+func appendToken(token []interface{}, key interface{}) []interface{} {
+	t := make([]interface{}, len(token), len(token)+1)
+	copy(t, token)
+	return append(t, key)
+}
+
+func isSamePath(a, b []interface{}) bool {
+	return len(a) == len(b) && isRelatedPath(a, b)
+}
+
+// parsePattern supports $, .key, ..key, .*, ..*, [n], [*] and ['key'] / ["key"]
+
+func parsePattern(pattern string) ([]patternStep, error) {
+	if !strings.HasPrefix(pattern, "$") {
+		return nil, fmt.Errorf("%w: %s", invalidPatternError, pattern)
+	}
+
+	steps := make([]patternStep, 0)
+	rest := pattern[1:]
+
+	for len(rest) > 0 {
+		step := patternStep{}
+
+		if strings.HasPrefix(rest, "..") {
+			step.recursive = true
+			rest = rest[2:]
+		} else if rest[0] == '.' {
+			rest = rest[1:]
+		} else if rest[0] != '[' {
+			return nil, fmt.Errorf("%w: %s", invalidPatternError, pattern)
+		}
+
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("%w: %s", invalidPatternError, pattern)
+		}
+
+		if rest[0] == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %s", invalidPatternError, pattern)
+			}
+
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			if inner == "*" {
+				step.wildcard = true
+			} else if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				step.key = inner[1 : len(inner)-1]
+			} else if idx, err := strconv.Atoi(inner); err == nil {
+				step.isIndex = true
+				step.index = idx
+			} else {
+				return nil, fmt.Errorf("%w: %s", invalidPatternError, pattern)
+			}
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			name := rest[:end]
+			rest = rest[end:]
+
+			if name == "" {
+				return nil, fmt.Errorf("%w: %s", invalidPatternError, pattern)
+			}
+
+			if name == "*" {
+				step.wildcard = true
+			} else {
+				step.key = name
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: %s", invalidPatternError, pattern)
+	}
+
+	return steps, nil
+}
+
+func matchPattern(steps []patternStep, token []interface{}) bool {
+	if len(steps) == 0 {
+		return len(token) == 0
+	}
+
+	step := steps[0]
+
+	if step.recursive {
+		for idx := range token {
+			if step.match(token[idx]) && matchPattern(steps[1:], token[idx+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return len(token) > 0 && step.match(token[0]) && matchPattern(steps[1:], token[1:])
+}
+
+func (m patternStep) match(token interface{}) bool {
+	if m.wildcard {
+		return true
+	}
+
+	switch t := token.(type) {
+	case int:
+		return m.isIndex && m.index == t
+	case string:
+		return !m.isIndex && m.key == t
+	}
+
+	return false
+}
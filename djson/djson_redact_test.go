@@ -0,0 +1,95 @@
+package djson
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	jsonDoc := `{
+		"user": {"name":"Ricardo Longa","password":"p@ssw0rd","ssn":"900101-1234567"},
+		"cards": [{"number":"4111111111111111","owner":"Ricardo"},{"number":"5500000000000004","owner":"Ricardo"}],
+		"token": "abcdef"
+	}`
+
+	policy := NewRedactPolicy("salt")
+	if err := policy.AddKey(`(?i)^password$`, REDACT_REMOVE); err != nil {
+		log.Fatal(err)
+	}
+	if err := policy.AddPattern(`$.cards[*].number`, REDACT_MASK); err != nil {
+		log.Fatal(err)
+	}
+	if err := policy.AddPath(`["user"]["ssn"]`, REDACT_REPLACE); err != nil {
+		log.Fatal(err)
+	}
+	if err := policy.AddPattern(`$..token`, REDACT_HASH); err != nil {
+		log.Fatal(err)
+	}
+
+	aJson := NewDJSON().Parse(jsonDoc)
+	bJson := aJson.Redact(policy)
+
+	log.Println(bJson.ToString())
+
+	if bJson.GetTypePath(`["user"]["password"]`) != "" {
+		log.Fatal("password not removed")
+	}
+
+	if bJson.GetAsStringPath(`["cards"][1]["number"]`) != "************0004" {
+		log.Fatal("card number not masked")
+	}
+
+	if bJson.GetAsStringPath(`["user"]["ssn"]`) != "***" {
+		log.Fatal("ssn not replaced")
+	}
+
+	if len(bJson.GetAsString("token")) != 64 || bJson.GetAsString("token") == "abcdef" {
+		log.Fatal("token not hashed")
+	}
+
+	if aJson.GetAsStringPath(`["user"]["password"]`) != "p@ssw0rd" {
+		log.Fatal("original document changed")
+	}
+}
+
+func TestRedactPolicyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redact")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "policy.json")
+	policyDoc := `{
+		"salt": "s3cr3t",
+		"replacement": "[hidden]",
+		"rules": [
+			{"key": "(?i)email", "action": "replace"},
+			{"pattern": "$[*].phone", "action": "remove"}
+		]
+	}`
+
+	if err := ioutil.WriteFile(filename, []byte(policyDoc), 0600); err != nil {
+		log.Fatal(err)
+	}
+
+	policy, err := LoadRedactPolicy(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	aJson := NewDJSON().Parse(`[{"Email":"a@b.com","phone":"010-1234-5678"}]`).Redact(policy)
+
+	log.Println(aJson.ToString())
+
+	if aJson.GetAsStringPath(`[0]["Email"]`) != "[hidden]" || aJson.GetTypePath(`[0]["phone"]`) != "" {
+		log.Fatal("policy from file not applied")
+	}
+
+	if _, err := ParseRedactPolicy(`{"rules":[{"key":"name","action":"encrypt"}]}`); err == nil {
+		log.Fatal("unknown action must fail")
+	}
+}
@@ -0,0 +1,344 @@
+package djson
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TableOptions controls RenderTable and RenderMarkdown. Columns are paths
+// (`["user"]["name"]`) or plain keys; empty means every key of the rows in
+// order of appearance. Headers replace the column names by index. A cell
+// wider than MaxWidth (0 for no limit) is truncated with "...".
+
+type TableOptions struct {
+	Columns  []string
+	Headers  []string
+	MaxWidth int
+}
+
+type renderTable struct {
+	headers []string
+	rows    [][]string
+	widths  []int
+	numeric []bool // every non-empty cell of the column is a number
+}
+
+// RenderTable renders an array of objects (or a single object) as an aligned
+// ASCII table. Width is measured in terminal columns, so East-Asian wide
+// characters count as two.
+
+func (m *DJSON) RenderTable(opts TableOptions) string {
+	t := m.newRenderTable(opts, false)
+
+	var sb strings.Builder
+
+	line := func() {
+		sb.WriteByte('+')
+		for _, w := range t.widths {
+			sb.WriteString(strings.Repeat("-", w+2))
+			sb.WriteByte('+')
+		}
+		sb.WriteByte('\n')
+	}
+
+	row := func(cells []string, align bool) {
+		sb.WriteByte('|')
+		for idx, cell := range cells {
+			sb.WriteByte(' ')
+			sb.WriteString(padCell(cell, t.widths[idx], align && t.numeric[idx]))
+			sb.WriteString(" |")
+		}
+		sb.WriteByte('\n')
+	}
+
+	line()
+	row(t.headers, false)
+	line()
+	for _, cells := range t.rows {
+		row(cells, true)
+	}
+	if len(t.rows) > 0 {
+		line()
+	}
+
+	return sb.String()
+}
+
+// RenderMarkdown renders the same table as RenderTable in Markdown. Number
+// columns are right-aligned and | in cells is escaped.
+
+func (m *DJSON) RenderMarkdown(opts TableOptions) string {
+	t := m.newRenderTable(opts, true)
+
+	var sb strings.Builder
+
+	row := func(cells []string, align bool) {
+		sb.WriteByte('|')
+		for idx, cell := range cells {
+			sb.WriteByte(' ')
+			sb.WriteString(padCell(cell, t.widths[idx], align && t.numeric[idx]))
+			sb.WriteString(" |")
+		}
+		sb.WriteByte('\n')
+	}
+
+	row(t.headers, false)
+
+	sb.WriteByte('|')
+	for idx, w := range t.widths {
+		if t.numeric[idx] {
+			sb.WriteString(strings.Repeat("-", w+1))
+			sb.WriteString(":|")
+		} else {
+			sb.WriteString(strings.Repeat("-", w+2))
+			sb.WriteByte('|')
+		}
+	}
+	sb.WriteByte('\n')
+
+	for _, cells := range t.rows {
+		row(cells, true)
+	}
+
+	return sb.String()
+}
+
+func (m *DJSON) newRenderTable(opts TableOptions, markdown bool) *renderTable {
+	rows := make([]*DJSON, 0)
+	if m.IsArray() {
+		for idx := 0; idx < m.Length(); idx++ {
+			if row, ok := m.Get(idx); ok {
+				rows = append(rows, row)
+			}
+		}
+	} else if m.IsObject() {
+		rows = append(rows, m)
+	}
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			for _, k := range row.GetKeys() {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		}
+	}
+
+	t := &renderTable{
+		headers: make([]string, len(columns)),
+		widths:  make([]int, len(columns)),
+		numeric: make([]bool, len(columns)),
+	}
+
+	tokens := make([][]interface{}, len(columns))
+	for idx, col := range columns {
+		tokens[idx] = keyOrPathTokens(col)
+
+		header := col
+		if idx < len(opts.Headers) {
+			header = opts.Headers[idx]
+		}
+		t.headers[idx] = formatCell(header, opts.MaxWidth, markdown)
+		t.numeric[idx] = true
+	}
+
+	hasValue := make([]bool, len(columns))
+
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for idx := range columns {
+			v, ok := row.getByTokens(tokens[idx]...)
+			if !ok {
+				continue
+			}
+
+			hasValue[idx] = true
+			if !v.IsInt() && !v.IsFloat() {
+				t.numeric[idx] = false
+			}
+
+			var text string
+			switch {
+			case v.IsString():
+				text = v.String
+			case v.IsObject() || v.IsArray():
+				text = v.ToStringWith(SerializeOptions{})
+			default:
+				text = v.ToString()
+			}
+			cells[idx] = formatCell(text, opts.MaxWidth, markdown)
+		}
+		t.rows = append(t.rows, cells)
+	}
+
+	for idx := range columns {
+		t.numeric[idx] = t.numeric[idx] && hasValue[idx]
+		t.widths[idx] = displayWidth(t.headers[idx])
+		if markdown && t.widths[idx] < 3 { // at least ---
+			t.widths[idx] = 3
+		}
+		for _, cells := range t.rows {
+			if w := displayWidth(cells[idx]); w > t.widths[idx] {
+				t.widths[idx] = w
+			}
+		}
+	}
+
+	return t
+}
+
+// formatCell puts a value on one line, escapes | for Markdown and truncates
+// it to maxWidth, which the escapes count in.
+
+func formatCell(s string, maxWidth int, markdown bool) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+
+	if markdown {
+		s = strings.Replace(s, "|", `\|`, -1)
+	}
+
+	return truncateWidth(s, maxWidth)
+}
+
+func truncateWidth(s string, maxWidth int) string {
+	if maxWidth <= 0 || displayWidth(s) <= maxWidth {
+		return s
+	}
+
+	suffix := "..."
+	if maxWidth <= len(suffix) {
+		suffix = ""
+	}
+
+	limit := maxWidth - len(suffix)
+	width := 0
+	for idx, r := range s {
+		w := runeWidth(r)
+		if width+w > limit {
+			if r == '|' && idx > 0 && s[idx-1] == '\\' { // keep \| whole
+				idx--
+			}
+			return s[:idx] + suffix
+		}
+		width += w
+	}
+
+	return s
+}
+
+func padCell(s string, width int, right bool) string {
+	pad := strings.Repeat(" ", width-displayWidth(s))
+	if right {
+		return pad + s
+	}
+	return s + pad
+}
+
+// RenderTree renders a document as an indented tree with the type of every
+// value, keys in document order.
+
+func (m *DJSON) RenderTree() string {
+	var sb strings.Builder
+	writeTree(&sb, "", m.GetAsInterface(), 0)
+	return sb.String()
+}
+
+func writeTree(sb *strings.Builder, label string, v interface{}, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	if label != "" {
+		sb.WriteString(label)
+	}
+
+	switch t := v.(type) {
+	case *DO:
+		keys := t.Keys()
+		if label != "" {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString("(object, " + strconv.Itoa(len(keys)) + ")\n")
+
+		for _, k := range keys {
+			ev, _ := t.Get(k)
+			writeTree(sb, k, ev, depth+1)
+		}
+	case *DA:
+		if label != "" {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString("(array, " + strconv.Itoa(t.Size()) + ")\n")
+
+		for idx := 0; idx < t.Size(); idx++ {
+			writeTree(sb, "["+strconv.Itoa(idx)+"]", t.Element[idx], depth+1)
+		}
+	default:
+		if label != "" {
+			sb.WriteString(": ")
+		}
+		typ := "string"
+		switch v.(type) {
+		case nil:
+			typ = "null"
+		case bool:
+			typ = "bool"
+		case int64:
+			typ = "int"
+		case float64:
+			typ = "float"
+		}
+
+		sb.WriteString(marshalString(v, SerializeOptions{}))
+		sb.WriteString(" (" + typ + ")\n")
+	}
+}
+
+// wideRunes are the ranges displayed with two columns (East Asian Wide and
+// Fullwidth, and emoji).
+var wideRunes = [][2]rune{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0},
+	{0x23F3, 0x23F3}, {0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F},
+	{0x2693, 0x2693}, {0x26A1, 0x26A1}, {0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5},
+	{0x26CE, 0x26CE}, {0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5},
+	{0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B}, {0x2728, 0x2728},
+	{0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27B0, 0x27B0}, {0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55},
+	{0x2E80, 0x303E}, {0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF},
+	{0xA960, 0xA97F}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19}, {0xFE30, 0xFE6F},
+	{0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF}, {0x1F18E, 0x1F18E},
+	{0x1F191, 0x1F19A}, {0x1F200, 0x1F251}, {0x1F300, 0x1F64F}, {0x1F680, 0x1F6FF}, {0x1F900, 0x1F9FF},
+	{0x1FA70, 0x1FAFF}, {0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+func runeWidth(r rune) int {
+	if unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) ||
+		(r >= 0x1160 && r <= 0x11FF) { // Hangul medial vowels and final consonants
+		return 0
+	}
+
+	idx := sort.Search(len(wideRunes), func(i int) bool {
+		return wideRunes[i][1] >= r
+	})
+	if idx < len(wideRunes) && wideRunes[idx][0] <= r {
+		return 2
+	}
+
+	return 1
+}
+
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
@@ -0,0 +1,75 @@
+package djson
+
+import (
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRenderTable(t *testing.T) {
+	aJson := NewDJSON().Parse(`[
+		{"id": 1, "user": {"name": "홍길동"}, "memo": "first\nline", "tags": ["a"]},
+		{"id": 20, "user": {"name": "Kim"}, "memo": "a very long memo which is truncated"}
+	]`)
+
+	opts := TableOptions{
+		Columns:  []string{"id", `["user"]["name"]`, "memo", "tags"},
+		Headers:  []string{"ID", "Name"},
+		MaxWidth: 12,
+	}
+
+	table := aJson.RenderTable(opts)
+	log.Println("\n" + table)
+
+	expected := `+----+--------+--------------+-------+
+| ID | Name   | memo         | tags  |
++----+--------+--------------+-------+
+|  1 | 홍길동 | first line   | ["a"] |
+| 20 | Kim    | a very lo... |       |
++----+--------+--------------+-------+
+`
+	if table != expected {
+		log.Fatal("table is not aligned")
+	}
+
+	md := aJson.RenderMarkdown(TableOptions{Columns: []string{"id", `["user"]["name"]`}})
+	log.Println("\n" + md)
+
+	if !strings.HasPrefix(md, "| id  | [\"user\"][\"name\"] |\n|----:|") {
+		log.Fatal("wrong markdown")
+	}
+
+	if cell := NewDJSON().Parse(`{"a": "x|y"}`).RenderMarkdown(TableOptions{}); !strings.Contains(cell, `x\|y`) {
+		log.Fatal("| must be escaped")
+	}
+
+	// escapes count in the width and \| is not split
+	md = NewDJSON().Parse(`[{"a": "ab|cde"}, {"a": "abc|def"}]`).RenderMarkdown(TableOptions{MaxWidth: 7})
+	log.Println("\n" + md)
+
+	if md != "| a       |\n|---------|\n| ab\\|cde |\n| abc...  |\n" {
+		log.Fatal("wrong markdown width")
+	}
+
+	if displayWidth("a홍é😀") != 6 || truncateWidth("홍길동", 5) != "홍..." {
+		log.Fatal("wrong width")
+	}
+}
+
+func TestRenderTree(t *testing.T) {
+	tree := NewDJSON().Parse(`{"name": "Hong", "items": [{"qty": 2}, null], "vip": true, "score": 1.5}`).RenderTree()
+	log.Println("\n" + tree)
+
+	expected := `(object, 4)
+  name: "Hong" (string)
+  items (array, 2)
+    [0] (object, 1)
+      qty: 2 (int)
+    [1]: null (null)
+  vip: true (bool)
+  score: 1.5 (float)
+`
+	if tree != expected {
+		log.Fatal("wrong tree")
+	}
+}
@@ -0,0 +1,361 @@
+package djson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// SerializeOptions controls Serialize. The zero value writes compact JSON in
+// original key order without HTML escaping and with shortest float format.
+// FloatPrecision 0 means the shortest representation (as encoding/json);
+// FloatFormat is one of 'f', 'e', 'g' and only used with FloatPrecision > 0.
+
+type SerializeOptions struct {
+	Indent         string
+	SortKeys       bool
+	EscapeHTML     bool
+	ASCIIOnly      bool
+	FloatPrecision int
+	FloatFormat    byte
+}
+
+const serializeFlushSize = 4096
+
+// output of encoding/json, used by ToString and ToStringPretty
+var toStringOptions = SerializeOptions{SortKeys: true, EscapeHTML: true}
+var toStringPrettyOptions = SerializeOptions{SortKeys: true, EscapeHTML: true, Indent: "   "}
+
+type serializer struct {
+	w    io.Writer // nil to keep everything in buf
+	buf  []byte
+	opts SerializeOptions
+	err  error
+}
+
+var serializerPool = sync.Pool{
+	New: func() interface{} {
+		return &serializer{buf: make([]byte, 0, 1024)}
+	},
+}
+
+const maxPooledBufferSize = 64 * 1024
+
+func marshalString(v interface{}, opts SerializeOptions) string {
+	s := serializerPool.Get().(*serializer)
+	s.w = nil
+	s.err = nil
+	s.opts = opts
+	s.buf = s.buf[:0]
+
+	s.writeValue(v, 0)
+
+	str, err := string(s.buf), s.err
+
+	if cap(s.buf) <= maxPooledBufferSize {
+		serializerPool.Put(s)
+	}
+
+	if err != nil {
+		return ""
+	}
+
+	return str
+}
+
+func (m *DJSON) Serialize(w io.Writer, opts SerializeOptions) error {
+	s := &serializer{
+		w:    w,
+		buf:  make([]byte, 0, serializeFlushSize*2),
+		opts: opts,
+	}
+
+	s.writeValue(m.GetAsInterface(), 0)
+	s.flush()
+
+	return s.err
+}
+
+func (m *DJSON) ToStringWith(opts SerializeOptions) string {
+	var sb bytes.Buffer
+	if err := m.Serialize(&sb, opts); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+func (m *serializer) flush() {
+	if m.w == nil || m.err != nil || len(m.buf) == 0 {
+		return
+	}
+
+	_, m.err = m.w.Write(m.buf)
+	m.buf = m.buf[:0]
+}
+
+func (m *serializer) newline(depth int) {
+	if m.opts.Indent == "" {
+		return
+	}
+
+	m.buf = append(m.buf, '\n')
+	for idx := 0; idx < depth; idx++ {
+		m.buf = append(m.buf, m.opts.Indent...)
+	}
+}
+
+func (m *serializer) writeValue(v interface{}, depth int) {
+	if m.err != nil {
+		return
+	}
+
+	if m.w != nil && len(m.buf) >= serializeFlushSize {
+		m.flush()
+	}
+
+	switch t := v.(type) {
+	case nil:
+		m.buf = append(m.buf, "null"...)
+	case string:
+		m.writeString(t)
+	case bool:
+		m.buf = strconv.AppendBool(m.buf, t)
+	case int:
+		m.buf = strconv.AppendInt(m.buf, int64(t), 10)
+	case int8:
+		m.buf = strconv.AppendInt(m.buf, int64(t), 10)
+	case int16:
+		m.buf = strconv.AppendInt(m.buf, int64(t), 10)
+	case int32:
+		m.buf = strconv.AppendInt(m.buf, int64(t), 10)
+	case int64:
+		m.buf = strconv.AppendInt(m.buf, t, 10)
+	case uint:
+		m.buf = strconv.AppendUint(m.buf, uint64(t), 10)
+	case uint8:
+		m.buf = strconv.AppendUint(m.buf, uint64(t), 10)
+	case uint16:
+		m.buf = strconv.AppendUint(m.buf, uint64(t), 10)
+	case uint32:
+		m.buf = strconv.AppendUint(m.buf, uint64(t), 10)
+	case uint64:
+		m.buf = strconv.AppendUint(m.buf, t, 10)
+	case float32:
+		m.writeFloat(float64(t), 32)
+	case float64:
+		m.writeFloat(t, 64)
+	case *DO:
+		m.writeObject(t, depth)
+	case DO:
+		m.writeObject(&t, depth)
+	case *DA:
+		m.writeArray(t, depth)
+	case DA:
+		m.writeArray(&t, depth)
+	case *DJSON:
+		m.writeValue(t.GetAsInterface(), depth)
+	case DJSON:
+		m.writeValue(t.GetAsInterface(), depth)
+	default:
+		jsonByte, err := json.Marshal(t)
+		if err != nil {
+			m.err = err
+			return
+		}
+		m.buf = append(m.buf, jsonByte...)
+	}
+}
+
+func (m *serializer) writeObject(do *DO, depth int) {
+	if do.lazy != nil && m.writeRaw(do.lazy) {
+		return
+	}
+
+	if do.Length() == 0 {
+		m.buf = append(m.buf, "{}"...)
+		return
+	}
+
+	var keys []string
+
+	if m.opts.SortKeys {
+		keys = make([]string, 0, len(do.Map))
+		for k := range do.Map {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	} else {
+		keys = do.Keys()
+	}
+
+	m.buf = append(m.buf, '{')
+
+	for idx, k := range keys {
+		if idx > 0 {
+			m.buf = append(m.buf, ',')
+		}
+
+		m.newline(depth + 1)
+		m.writeString(k)
+		m.buf = append(m.buf, ':')
+		if m.opts.Indent != "" {
+			m.buf = append(m.buf, ' ')
+		}
+
+		m.writeValue(do.Map[k], depth+1)
+	}
+
+	m.newline(depth)
+	m.buf = append(m.buf, '}')
+}
+
+func (m *serializer) writeArray(da *DA, depth int) {
+	if da.lazy != nil && m.writeRaw(da.lazy) {
+		return
+	}
+
+	if da.Length() == 0 {
+		m.buf = append(m.buf, "[]"...)
+		return
+	}
+
+	m.buf = append(m.buf, '[')
+
+	for idx := range da.Element {
+		if idx > 0 {
+			m.buf = append(m.buf, ',')
+		}
+
+		m.newline(depth + 1)
+		m.writeValue(da.Element[idx], depth+1)
+	}
+
+	m.newline(depth)
+	m.buf = append(m.buf, ']')
+}
+
+// writeRaw writes an untouched lazy subtree as it was in the parsed document.
+// Indented, ASCII-only and sorted output has to be re-encoded, and so has
+// HTML-escaped output if the raw bytes have a character to escape, and a
+// subtree eager decoding alters.
+
+func (m *serializer) writeRaw(raw *lazyRaw) bool {
+	if m.opts.Indent != "" || m.opts.ASCIIOnly || m.opts.SortKeys || raw.altered {
+		return false
+	}
+
+	if m.opts.EscapeHTML && bytes.ContainsAny(raw.data, "<>&") {
+		return false
+	}
+
+	m.buf = append(m.buf, raw.data...)
+	return true
+}
+
+func (m *serializer) writeFloat(f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		m.buf = append(m.buf, "null"...)
+		return
+	}
+
+	if m.opts.FloatPrecision > 0 {
+		format := m.opts.FloatFormat
+		if format != 'e' && format != 'g' {
+			format = 'f'
+		}
+		m.buf = strconv.AppendFloat(m.buf, f, format, m.opts.FloatPrecision, bits)
+		return
+	}
+
+	// same as encoding/json
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	m.buf = strconv.AppendFloat(m.buf, f, format, -1, bits)
+
+	if format == 'e' { // clean up e-09 to e-9
+		n := len(m.buf)
+		if n >= 4 && m.buf[n-4] == 'e' && m.buf[n-3] == '-' && m.buf[n-2] == '0' {
+			m.buf[n-2] = m.buf[n-1]
+			m.buf = m.buf[:n-1]
+		}
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+func (m *serializer) writeString(s string) {
+	m.buf = append(m.buf, '"')
+
+	start := 0
+	for idx := 0; idx < len(s); {
+		if b := s[idx]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && (!m.opts.EscapeHTML || (b != '<' && b != '>' && b != '&')) {
+				idx++
+				continue
+			}
+
+			m.buf = append(m.buf, s[start:idx]...)
+
+			switch b {
+			case '"', '\\':
+				m.buf = append(m.buf, '\\', b)
+			case '\n':
+				m.buf = append(m.buf, '\\', 'n')
+			case '\r':
+				m.buf = append(m.buf, '\\', 'r')
+			case '\t':
+				m.buf = append(m.buf, '\\', 't')
+			default:
+				m.buf = append(m.buf, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+
+			idx++
+			start = idx
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[idx:])
+
+		if r == utf8.RuneError && size == 1 {
+			m.buf = append(m.buf, s[start:idx]...)
+			m.buf = append(m.buf, `\ufffd`...)
+			idx += size
+			start = idx
+			continue
+		}
+
+		if r == '\u2028' || r == '\u2029' || m.opts.ASCIIOnly {
+			m.buf = append(m.buf, s[start:idx]...)
+			if r > 0xFFFF {
+				r -= 0x10000
+				m.appendRuneEscape(0xD800 + (r>>10)&0x3FF)
+				m.appendRuneEscape(0xDC00 + r&0x3FF)
+			} else {
+				m.appendRuneEscape(r)
+			}
+			idx += size
+			start = idx
+			continue
+		}
+
+		idx += size
+	}
+
+	m.buf = append(m.buf, s[start:]...)
+	m.buf = append(m.buf, '"')
+}
+
+func (m *serializer) appendRuneEscape(r rune) {
+	m.buf = append(m.buf, '\\', 'u', hexDigits[(r>>12)&0xF], hexDigits[(r>>8)&0xF], hexDigits[(r>>4)&0xF], hexDigits[r&0xF])
+}
@@ -0,0 +1,56 @@
+package djson
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"testing"
+)
+
+func TestSerializeOrder(t *testing.T) {
+	aJson := NewDJSON().Parse(`{"name":"Ricardo Longa","idade":28,"skills":["Golang","Android"],"address":{"zip":"04524","city":"Seoul"}}`)
+
+	var buf bytes.Buffer
+	if err := aJson.Serialize(&buf, SerializeOptions{}); err != nil {
+		log.Fatal(err)
+	}
+
+	if buf.String() != `{"name":"Ricardo Longa","idade":28,"skills":["Golang","Android"],"address":{"zip":"04524","city":"Seoul"}}` {
+		log.Fatal("original key order not kept: ", buf.String())
+	}
+
+	sorted := aJson.ToStringWith(SerializeOptions{SortKeys: true, Indent: "  "})
+	log.Println(sorted)
+
+	if sorted != "{\n  \"address\": {\n    \"city\": \"Seoul\",\n    \"zip\": \"04524\"\n  },\n  \"idade\": 28,\n  \"name\": \"Ricardo Longa\",\n  \"skills\": [\n    \"Golang\",\n    \"Android\"\n  ]\n}" {
+		log.Fatal("sorted pretty output mismatch")
+	}
+}
+
+func TestSerializeEscape(t *testing.T) {
+	aJson := NewObjectJSON("html", "<a href=\"x\">&</a>", "kor", "한글\u2028", "ctrl", "a\tb\x01", "pi", 3.14159265, "tiny", 0.0000001)
+
+	jsonByte, _ := json.Marshal(ConverObjectToMap(aJson.Object))
+
+	if aJson.ToStringWith(SerializeOptions{SortKeys: true, EscapeHTML: true}) != string(jsonByte) {
+		log.Fatal("output differs from encoding/json")
+	}
+
+	noEscape := aJson.ToStringWith(SerializeOptions{SortKeys: true})
+	if !bytes.Contains([]byte(noEscape), []byte(`<a href=\"x\">&</a>`)) {
+		log.Fatal("HTML must not be escaped: ", noEscape)
+	}
+
+	ascii := aJson.ToStringWith(SerializeOptions{SortKeys: true, ASCIIOnly: true})
+	if !bytes.Contains([]byte(ascii), []byte(`"\ud55c\uae00\u2028"`)) {
+		log.Fatal("ASCII only output failed: ", ascii)
+	}
+
+	prec := NewFloatJSON(3.14159265).ToStringWith(SerializeOptions{FloatPrecision: 2})
+	if prec != "3.14" {
+		log.Fatal("float precision failed: ", prec)
+	}
+
+	log.Println(noEscape)
+	log.Println(ascii)
+}
@@ -0,0 +1,249 @@
+package djson
+
+// Set operations treat arrays as sets: the result keeps the first of equal
+// elements in order, and its elements are copies. Elements are equal by
+// Equal, or by the value at idPath (a path or a plain key) when given, in
+// which case an element without it is compared as a whole. A non-array is an
+// empty set.
+
+type valueSet struct {
+	idTokens []interface{}
+	buckets  map[string][]*DJSON
+}
+
+func newValueSet(idPath []string) *valueSet {
+	s := &valueSet{buckets: make(map[string][]*DJSON)}
+	if len(idPath) > 0 && idPath[0] != "" {
+		s.idTokens = keyOrPathTokens(idPath[0])
+	}
+	return s
+}
+
+// identity returns the hash key and the value compared by Equal. Equal
+// values have the same key; the type prefix only splits int and float.
+
+func (m *valueSet) identity(v *DJSON) (string, *DJSON) {
+	prefix := "v"
+	if m.idTokens != nil {
+		if id, ok := v.getByTokens(m.idTokens...); ok {
+			prefix, v = "id", id
+		}
+	}
+
+	return prefix + v.GetType() + ":" + marshalString(v.GetAsInterface(), SerializeOptions{SortKeys: true}), v
+}
+
+func (m *valueSet) has(v *DJSON) bool {
+	key, id := m.identity(v)
+	for _, e := range m.buckets[key] {
+		if e.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// add returns false if an equal element is already in the set.
+
+func (m *valueSet) add(v *DJSON) bool {
+	if m.has(v) {
+		return false
+	}
+
+	key, id := m.identity(v)
+	m.buckets[key] = append(m.buckets[key], id)
+	return true
+}
+
+func setElements(m *DJSON) []*DJSON {
+	if m == nil || !m.IsArray() {
+		return nil
+	}
+
+	elems := make([]*DJSON, 0, m.Length())
+	for idx := 0; idx < m.Length(); idx++ {
+		if v, ok := m.Get(idx); ok {
+			elems = append(elems, v)
+		}
+	}
+
+	return elems
+}
+
+// setOf returns the set of t and its distinct elements.
+
+func setOf(t *DJSON, idPath []string) (*valueSet, []*DJSON) {
+	s := newValueSet(idPath)
+	elems := make([]*DJSON, 0)
+	for _, v := range setElements(t) {
+		if s.add(v) {
+			elems = append(elems, v)
+		}
+	}
+	return s, elems
+}
+
+func newSetResult(elems ...[]*DJSON) *DJSON {
+	ret := NewDJSON(JSON_ARRAY)
+	for _, each := range elems {
+		for _, v := range each {
+			ret.Array.PushBack(cloneValue(v.GetAsInterface()))
+		}
+	}
+	return ret
+}
+
+// Unique returns the distinct elements of m.
+
+func (m *DJSON) Unique(idPath ...string) *DJSON {
+	_, elems := setOf(m, idPath)
+	return newSetResult(elems)
+}
+
+// Union returns the distinct elements of m followed by those of t which are
+// not in m.
+
+func (m *DJSON) Union(t *DJSON, idPath ...string) *DJSON {
+	s, elems := setOf(m, idPath)
+	for _, v := range setElements(t) {
+		if s.add(v) {
+			elems = append(elems, v)
+		}
+	}
+	return newSetResult(elems)
+}
+
+// Intersect returns the distinct elements of m which are in t.
+
+func (m *DJSON) Intersect(t *DJSON, idPath ...string) *DJSON {
+	ts, _ := setOf(t, idPath)
+	_, elems := setOf(m, idPath)
+
+	ret := make([]*DJSON, 0)
+	for _, v := range elems {
+		if ts.has(v) {
+			ret = append(ret, v)
+		}
+	}
+	return newSetResult(ret)
+}
+
+// Difference returns the distinct elements of m which are not in t.
+
+func (m *DJSON) Difference(t *DJSON, idPath ...string) *DJSON {
+	ts, _ := setOf(t, idPath)
+	_, elems := setOf(m, idPath)
+	return newSetResult(setMinus(elems, ts))
+}
+
+// SymmetricDifference returns the elements of m not in t followed by those
+// of t not in m.
+
+func (m *DJSON) SymmetricDifference(t *DJSON, idPath ...string) *DJSON {
+	ms, melems := setOf(m, idPath)
+	ts, telems := setOf(t, idPath)
+	return newSetResult(setMinus(melems, ts), setMinus(telems, ms))
+}
+
+func setMinus(elems []*DJSON, s *valueSet) []*DJSON {
+	ret := make([]*DJSON, 0)
+	for _, v := range elems {
+		if !s.has(v) {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// Pick returns a copy of the object with only the given keys or paths.
+// Paths through objects keep their nesting: `["user"]["name"]` gives
+// {"user": {"name": ...}}. Missing keys and paths through arrays are
+// skipped.
+
+func (m *DJSON) Pick(paths ...string) *DJSON {
+	ret := NewDJSON(JSON_OBJECT)
+	if !m.IsObject() {
+		return ret
+	}
+
+	for _, path := range paths {
+		tokens := keyOrPathTokens(path)
+
+		v, ok := m.getByTokens(tokens...)
+		if !ok || len(tokens) == 0 {
+			continue
+		}
+
+		keys := make([]string, len(tokens))
+		for idx := range tokens {
+			if keys[idx], ok = tokens[idx].(string); !ok {
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		do := ret.Object
+		for _, k := range keys[:len(keys)-1] {
+			next, ok := do.GetAsObject(k)
+			if !ok {
+				next = NewObject()
+				do.Put(k, next)
+			}
+			do = next
+		}
+		do.Put(keys[len(keys)-1], cloneValue(v.GetAsInterface()))
+	}
+
+	return ret
+}
+
+// Omit returns a copy of m without the given keys or paths.
+
+func (m *DJSON) Omit(paths ...string) *DJSON {
+	ret := m.Clone()
+
+	for _, path := range paths {
+		tokens := keyOrPathTokens(path)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		if parent, ok := ret.getByTokens(tokens[:len(tokens)-1]...); ok {
+			parent.Remove(tokens[len(tokens)-1])
+		}
+	}
+
+	return ret
+}
+
+// RenameKeys returns a copy of the object with keys renamed by names (old to
+// new), keeping their order. A renamed key replaces an existing one.
+
+func (m *DJSON) RenameKeys(names map[string]string) *DJSON {
+	ret := NewDJSON(JSON_OBJECT)
+	if !m.IsObject() {
+		return ret
+	}
+
+	renamed := make(map[string]bool)
+
+	for _, k := range m.Object.Keys() {
+		v, _ := m.Object.Get(k)
+
+		name, ok := names[k]
+		if !ok {
+			if renamed[k] {
+				continue
+			}
+			name = k
+		}
+
+		renamed[name] = ok
+		ret.Object.Remove(name)
+		ret.Object.Put(name, cloneValue(v))
+	}
+
+	return ret
+}
@@ -0,0 +1,65 @@
+package djson
+
+import (
+	"log"
+	"testing"
+)
+
+func TestSetOperation(t *testing.T) {
+	a := NewDJSON().Parse(`[1, 2, 2, {"sku": "x1", "qty": 1}, [1, 2], 1.0]`)
+	b := NewDJSON().Parse(`[2, 3, {"qty": 1, "sku": "x1"}, [2, 1]]`)
+
+	cases := map[string]*DJSON{
+		`[1,2,{"qty":1,"sku":"x1"},[1,2],1]`:         a.Unique(),
+		`[1,2,{"qty":1,"sku":"x1"},[1,2],1,3,[2,1]]`: a.Union(b),
+		`[2,{"qty":1,"sku":"x1"}]`:                   a.Intersect(b),
+		`[1,[1,2],1]`:                                a.Difference(b),
+		`[1,[1,2],1,3,[2,1]]`:                        a.SymmetricDifference(b),
+	}
+
+	for expected, ret := range cases {
+		if ret.ToString() != expected {
+			log.Fatal("expected ", expected, " but ", ret.ToString())
+		}
+	}
+
+	// reconcile inventories by sku
+	mine := NewDJSON().Parse(`[{"sku": "x1", "qty": 1}, {"sku": "y2", "qty": 5}]`)
+	theirs := NewDJSON().Parse(`[{"sku": "x1", "qty": 3}, {"sku": "z3", "qty": 2}]`)
+
+	if ret := mine.Difference(theirs, "sku"); ret.Length() != 1 || ret.GetAsStringPath(`[0]["sku"]`) != "y2" {
+		log.Fatal("wrong difference by sku: ", ret.ToString())
+	}
+
+	if ret := mine.Union(theirs, `["sku"]`); ret.Length() != 3 || ret.GetAsIntPath(`[0]["qty"]`) != 1 {
+		log.Fatal("wrong union by sku: ", ret.ToString())
+	}
+
+	// results are copies
+	ret := mine.Intersect(theirs, "sku")
+	ret.UpdatePath(`[0]["qty"]`, 100)
+	if mine.GetAsIntPath(`[0]["qty"]`) != 1 {
+		log.Fatal("input must not be changed")
+	}
+}
+
+func TestPickOmitRename(t *testing.T) {
+	aJson := NewDJSON().Parse(`{"id": 1, "user": {"name": "Hong", "phone": "010"}, "items": [{"sku": "x1"}], "memo": "x"}`)
+
+	if ret := aJson.Pick("id", `["user"]["name"]`, "nope"); ret.ToString() != `{"id":1,"user":{"name":"Hong"}}` {
+		log.Fatal("wrong pick: ", ret.ToString())
+	}
+
+	if ret := aJson.Omit("memo", `["user"]["phone"]`, `["items"][0]`); ret.ToString() != `{"id":1,"items":[],"user":{"name":"Hong"}}` {
+		log.Fatal("wrong omit: ", ret.ToString())
+	}
+
+	if !aJson.HasKey("memo") {
+		log.Fatal("input must not be changed")
+	}
+
+	ret := NewDJSON().Parse(`{"a": 1, "b": 2, "c": 3}`).RenameKeys(map[string]string{"a": "b", "c": "d"})
+	if ret.ToStringWith(SerializeOptions{}) != `{"b":1,"d":3}` {
+		log.Fatal("wrong rename: ", ret.ToStringWith(SerializeOptions{}))
+	}
+}
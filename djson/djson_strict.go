@@ -0,0 +1,230 @@
+package djson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	COERCE_NONE   = iota // the value must have the type; whole floats count as int
+	COERCE_STRING        // numeric and boolean strings convert, and scalars convert to string
+)
+
+const (
+	ACCESS_MISSING  = "missing"
+	ACCESS_TYPE     = "type"
+	ACCESS_OVERFLOW = "overflow"
+	ACCESS_LOSSY    = "lossy"
+)
+
+// AccessError is returned by the strict accessors (IntE, FloatE, StringE and
+// BoolE). Kind is one of ACCESS_*; Expected and Actual are type names as
+// GetType, where a null value is of type "null".
+
+type AccessError struct {
+	Kind     string
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *AccessError) Error() string {
+	switch e.Kind {
+	case ACCESS_MISSING:
+		return fmt.Sprintf("missing value at %s", e.Path)
+	case ACCESS_OVERFLOW:
+		return fmt.Sprintf("%s overflows %s at %s", e.Actual, e.Expected, e.Path)
+	case ACCESS_LOSSY:
+		return fmt.Sprintf("lossy conversion of %s to %s at %s", e.Actual, e.Expected, e.Path)
+	}
+
+	return fmt.Sprintf("expected %s but %s at %s", e.Expected, e.Actual, e.Path)
+}
+
+// strictGet returns the value at path, which is a path (`["a"][0]`), a
+// plain key, or "" for m itself.
+
+func (m *DJSON) strictGet(path string) (*DJSON, error) {
+	v, ok := m.getByTokens(keyOrPathTokens(path)...)
+	if !ok {
+		return nil, &AccessError{Kind: ACCESS_MISSING, Path: path}
+	}
+
+	return v, nil
+}
+
+func getCoerce(coerce []int) int {
+	if len(coerce) > 0 {
+		return coerce[0]
+	}
+	return COERCE_NONE
+}
+
+// IntE returns the integer at path. A float converts only if it is whole
+// and fits in int64; a string converts with COERCE_STRING.
+
+func (m *DJSON) IntE(path string, coerce ...int) (int64, error) {
+	v, err := m.strictGet(path)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v.JsonType {
+	case JSON_INT:
+		return v.Int, nil
+	case JSON_FLOAT:
+		return floatToInt(v.Float, path, "float")
+	case JSON_STRING:
+		if getCoerce(coerce) == COERCE_STRING {
+			s := strings.TrimSpace(v.String)
+
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err == nil {
+				return i, nil
+			}
+			if errors.Is(err, strconv.ErrRange) {
+				return 0, &AccessError{Kind: ACCESS_OVERFLOW, Path: path, Expected: "int", Actual: "string"}
+			}
+
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return floatToInt(f, path, "string")
+			}
+		}
+	}
+
+	return 0, &AccessError{Kind: ACCESS_TYPE, Path: path, Expected: "int", Actual: v.GetType()}
+}
+
+func floatToInt(f float64, path, actual string) (int64, error) {
+	if f >= math.MaxInt64 || f < math.MinInt64 || math.IsInf(f, 0) {
+		return 0, &AccessError{Kind: ACCESS_OVERFLOW, Path: path, Expected: "int", Actual: actual}
+	}
+
+	if f != math.Trunc(f) {
+		return 0, &AccessError{Kind: ACCESS_LOSSY, Path: path, Expected: "int", Actual: actual}
+	}
+
+	return int64(f), nil
+}
+
+// FloatE returns the number at path. An integer beyond 2^53 is lossy; a
+// string converts with COERCE_STRING.
+
+func (m *DJSON) FloatE(path string, coerce ...int) (float64, error) {
+	v, err := m.strictGet(path)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v.JsonType {
+	case JSON_FLOAT:
+		return v.Float, nil
+	case JSON_INT:
+		if v.Int > 1<<53 || v.Int < -(1<<53) {
+			return 0, &AccessError{Kind: ACCESS_LOSSY, Path: path, Expected: "float", Actual: "int"}
+		}
+		return float64(v.Int), nil
+	case JSON_STRING:
+		if getCoerce(coerce) == COERCE_STRING {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v.String), 64)
+			if err == nil {
+				return f, nil
+			}
+			if errors.Is(err, strconv.ErrRange) {
+				return 0, &AccessError{Kind: ACCESS_OVERFLOW, Path: path, Expected: "float", Actual: "string"}
+			}
+		}
+	}
+
+	return 0, &AccessError{Kind: ACCESS_TYPE, Path: path, Expected: "float", Actual: v.GetType()}
+}
+
+// StringE returns the string at path. With COERCE_STRING numbers and bools
+// are formatted.
+
+func (m *DJSON) StringE(path string, coerce ...int) (string, error) {
+	v, err := m.strictGet(path)
+	if err != nil {
+		return "", err
+	}
+
+	if v.JsonType == JSON_STRING {
+		return v.String, nil
+	}
+
+	if getCoerce(coerce) == COERCE_STRING {
+		switch v.JsonType {
+		case JSON_INT:
+			return strconv.FormatInt(v.Int, 10), nil
+		case JSON_FLOAT:
+			return strconv.FormatFloat(v.Float, 'f', -1, 64), nil
+		case JSON_BOOL:
+			return strconv.FormatBool(v.Bool), nil
+		}
+	}
+
+	return "", &AccessError{Kind: ACCESS_TYPE, Path: path, Expected: "string", Actual: v.GetType()}
+}
+
+// BoolE returns the bool at path. With COERCE_STRING "true" and "false"
+// (in any case) convert.
+
+func (m *DJSON) BoolE(path string, coerce ...int) (bool, error) {
+	v, err := m.strictGet(path)
+	if err != nil {
+		return false, err
+	}
+
+	switch v.JsonType {
+	case JSON_BOOL:
+		return v.Bool, nil
+	case JSON_STRING:
+		if getCoerce(coerce) == COERCE_STRING {
+			switch strings.ToLower(strings.TrimSpace(v.String)) {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+		}
+	}
+
+	return false, &AccessError{Kind: ACCESS_TYPE, Path: path, Expected: "bool", Actual: v.GetType()}
+}
+
+// Must* are the strict accessors which panic with the *AccessError.
+
+func (m *DJSON) MustInt(path string, coerce ...int) int64 {
+	v, err := m.IntE(path, coerce...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (m *DJSON) MustFloat(path string, coerce ...int) float64 {
+	v, err := m.FloatE(path, coerce...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (m *DJSON) MustString(path string, coerce ...int) string {
+	v, err := m.StringE(path, coerce...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (m *DJSON) MustBool(path string, coerce ...int) bool {
+	v, err := m.BoolE(path, coerce...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
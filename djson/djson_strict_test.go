@@ -0,0 +1,77 @@
+package djson
+
+import (
+	"errors"
+	"log"
+	"testing"
+)
+
+func TestStrictAccessor(t *testing.T) {
+	aJson := NewDJSON().Parse(`{
+		"amount": 0,
+		"price": 1500.0,
+		"rate": 0.5,
+		"qty": "12",
+		"big": 1e30,
+		"huge": "99999999999999999999",
+		"exact": 9007199254740993,
+		"paid": "TRUE",
+		"memo": null,
+		"order": {"items": [{"sku": "x1"}]}
+	}`)
+
+	if v, err := aJson.IntE("amount"); err != nil || v != 0 {
+		log.Fatal("zero must not be an error")
+	}
+
+	if v, err := aJson.IntE(`["price"]`); err != nil || v != 1500 {
+		log.Fatal("whole float must be an int")
+	}
+
+	if v, err := aJson.IntE("qty", COERCE_STRING); err != nil || v != 12 {
+		log.Fatal("numeric string must be coerced")
+	}
+
+	if v := aJson.MustString(`["order"]["items"][0]["sku"]`); v != "x1" {
+		log.Fatal("wrong string")
+	}
+
+	if v, err := aJson.BoolE("paid", COERCE_STRING); err != nil || !v {
+		log.Fatal("boolean string must be coerced")
+	}
+
+	if v, err := aJson.StringE("rate", COERCE_STRING); err != nil || v != "0.5" {
+		log.Fatal("number must be formatted")
+	}
+
+	cases := []struct {
+		err  error
+		kind string
+	}{
+		{func() error { _, err := aJson.IntE("missing"); return err }(), ACCESS_MISSING},
+		{func() error { _, err := aJson.IntE("qty"); return err }(), ACCESS_TYPE},
+		{func() error { _, err := aJson.IntE("rate"); return err }(), ACCESS_LOSSY},
+		{func() error { _, err := aJson.IntE("big"); return err }(), ACCESS_OVERFLOW},
+		{func() error { _, err := aJson.IntE("huge", COERCE_STRING); return err }(), ACCESS_OVERFLOW},
+		{func() error { _, err := aJson.FloatE("exact"); return err }(), ACCESS_LOSSY},
+		{func() error { _, err := aJson.StringE("memo"); return err }(), ACCESS_TYPE},
+		{func() error { _, err := aJson.BoolE("paid"); return err }(), ACCESS_TYPE},
+		{func() error { _, err := aJson.StringE(`["order"]["items"][1]`); return err }(), ACCESS_MISSING},
+	}
+
+	for idx, c := range cases {
+		var aerr *AccessError
+		if !errors.As(c.err, &aerr) || aerr.Kind != c.kind {
+			log.Fatal(idx, ": expected ", c.kind, " but ", c.err)
+		}
+		log.Println(c.err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			log.Fatal("MustInt must panic")
+		}
+	}()
+
+	aJson.MustInt("memo")
+}
@@ -0,0 +1,517 @@
+package djson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Template maps a source document to a new document. It is a DJSON in which
+//
+//   "{{ ["user"]["name"] | default("unknown") }}"   is replaced with the value at the path
+//   "Hello {{ ["user"]["name"] }}!"                  is a string with the value interpolated
+//   {"$each": "["items"]", "$do": {...}}             is an array rendered from each element
+//   {"$if": "["vip"]", "$then": ..., "$else": ...}   is a conditional value ("$unless" negates)
+//
+// Paths are relative to the current element inside "$do"; "$root" prefixes a
+// path from the source root and "$index" is the index of the current element.
+// Filters are default(<json>), int, float, string and bool, where the casts
+// follow GetAsInt, GetAsFloat, GetAsString and GetAsBool. A conditional value
+// without a matching branch is omitted, so "$if" also makes a key optional.
+// A Template is safe for concurrent use.
+
+type Template struct {
+	root templateNode
+}
+
+type templateCtx struct {
+	root  *DJSON
+	cur   *DJSON
+	index int
+}
+
+// templateNode renders a value. ok false means the value is omitted.
+type templateNode func(ctx *templateCtx) (v interface{}, ok bool)
+
+const (
+	templateScopeCurrent = iota
+	templateScopeRoot
+	templateScopeIndex
+)
+
+type templateExpr struct {
+	scope   int
+	token   []interface{}
+	filters []templateFilter
+}
+
+type templateFilter struct {
+	name string
+	arg  interface{}
+}
+
+func ParseTemplate(doc string) (*Template, error) {
+	tjson, err := NewDJSON().ParseWithOptions(doc, ParseOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", invalidTemplateError, err.Error())
+	}
+
+	return CompileTemplate(tjson)
+}
+
+func CompileTemplate(tmpl *DJSON) (*Template, error) {
+	if tmpl == nil {
+		return nil, invalidTemplateError
+	}
+
+	root, err := compileTemplateValue(tmpl.GetAsInterface())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{root: root}, nil
+}
+
+func (m *Template) Render(src *DJSON) *DJSON {
+	if src == nil {
+		src = NewDJSON()
+	}
+
+	v, ok := m.root(&templateCtx{root: src, cur: src})
+	if !ok {
+		return NewDJSON()
+	}
+
+	return NewDJSON().Put(v)
+}
+
+func compileTemplateValue(v interface{}) (templateNode, error) {
+	switch t := v.(type) {
+	case string:
+		return compileTemplateString(t)
+	case *DO:
+		if t.HasKey("$each") {
+			return compileTemplateEach(t)
+		}
+		if t.HasKey("$if") || t.HasKey("$unless") {
+			return compileTemplateIf(t)
+		}
+		return compileTemplateObject(t)
+	case *DA:
+		return compileTemplateArray(t)
+	}
+
+	return func(ctx *templateCtx) (interface{}, bool) {
+		return v, true
+	}, nil
+}
+
+func compileTemplateObject(do *DO) (templateNode, error) {
+	keys := make([]templateNode, 0, do.Size())
+	values := make([]templateNode, 0, do.Size())
+
+	for _, k := range do.Keys() {
+		if strings.HasPrefix(k, "$") {
+			return nil, fmt.Errorf("%w: unknown directive %s", invalidTemplateError, k)
+		}
+
+		key, err := compileTemplateString(k)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := compileTemplateValue(do.Map[k])
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	return func(ctx *templateCtx) (interface{}, bool) {
+		obj := NewObject()
+
+		for idx := range keys {
+			v, ok := values[idx](ctx)
+			if !ok {
+				continue
+			}
+
+			k, _ := keys[idx](ctx)
+			obj.Put(NewDJSON().Put(k).ToString(), v)
+		}
+
+		return obj, true
+	}, nil
+}
+
+func compileTemplateArray(da *DA) (templateNode, error) {
+	elements := make([]templateNode, 0, da.Size())
+
+	for idx := 0; idx < da.Size(); idx++ {
+		element, err := compileTemplateValue(da.Element[idx])
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+
+	return func(ctx *templateCtx) (interface{}, bool) {
+		arr := NewArray()
+
+		for idx := range elements {
+			if v, ok := elements[idx](ctx); ok {
+				arr.PushBack(v)
+			}
+		}
+
+		return arr, true
+	}, nil
+}
+
+func compileTemplateEach(do *DO) (templateNode, error) {
+	if err := checkTemplateDirectives(do, "$each", "$do"); err != nil {
+		return nil, err
+	}
+
+	source, err := parseTemplateExpr(do.GetAsString("$each"))
+	if err != nil {
+		return nil, err
+	}
+
+	body := templateNode(func(ctx *templateCtx) (interface{}, bool) {
+		return cloneValue(ctx.cur.GetAsInterface()), true
+	})
+
+	if do.HasKey("$do") {
+		if body, err = compileTemplateValue(do.Map["$do"]); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx *templateCtx) (interface{}, bool) {
+		arr := NewArray()
+
+		items, ok := source.eval(ctx)
+		if !ok || (!items.IsArray() && !items.IsObject()) {
+			return arr, true
+		}
+
+		keys := make([]interface{}, 0, items.Length())
+		if items.IsObject() { // values of an object
+			for _, k := range items.GetKeys() {
+				keys = append(keys, k)
+			}
+		} else {
+			for idx := 0; idx < items.Length(); idx++ {
+				keys = append(keys, idx)
+			}
+		}
+
+		for idx := range keys {
+			item, _ := items.Get(keys[idx])
+
+			each := &templateCtx{root: ctx.root, cur: item, index: idx}
+			if v, ok := body(each); ok {
+				arr.PushBack(v)
+			}
+		}
+
+		return arr, true
+	}, nil
+}
+
+func compileTemplateIf(do *DO) (templateNode, error) {
+	if err := checkTemplateDirectives(do, "$if", "$unless", "$then", "$else"); err != nil {
+		return nil, err
+	}
+
+	negate := do.HasKey("$unless")
+	if negate && do.HasKey("$if") {
+		return nil, fmt.Errorf("%w: $if and $unless together", invalidTemplateError)
+	}
+
+	condStr := do.GetAsString("$if")
+	if negate {
+		condStr = do.GetAsString("$unless")
+	}
+
+	cond, err := parseTemplateExpr(condStr)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := func(key string) (templateNode, error) {
+		if !do.HasKey(key) {
+			return nil, nil
+		}
+		return compileTemplateValue(do.Map[key])
+	}
+
+	then, err := branch("$then")
+	if err != nil {
+		return nil, err
+	}
+
+	otherwise, err := branch("$else")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *templateCtx) (interface{}, bool) {
+		v, ok := cond.eval(ctx)
+
+		node := otherwise
+		if (ok && isTemplateTruthy(v)) != negate {
+			node = then
+		}
+
+		if node == nil {
+			return nil, false
+		}
+
+		return node(ctx)
+	}, nil
+}
+
+func checkTemplateDirectives(do *DO, allowed ...string) error {
+	for _, k := range do.Keys() {
+		found := false
+		for _, a := range allowed {
+			if k == a {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%w: unexpected key %s with %s", invalidTemplateError, k, allowed[0])
+		}
+	}
+
+	return nil
+}
+
+func isTemplateTruthy(v *DJSON) bool {
+	switch v.JsonType {
+	case JSON_NULL:
+		return false
+	case JSON_BOOL:
+		return v.Bool
+	case JSON_INT:
+		return v.Int != 0
+	case JSON_FLOAT:
+		return v.Float != 0
+	case JSON_STRING:
+		return v.String != ""
+	}
+
+	return v.Length() > 0
+}
+
+// compileTemplateString compiles a string which is either a single
+// expression (typed value) or text with interpolated expressions.
+
+func compileTemplateString(s string) (templateNode, error) {
+	trimmed := strings.TrimSpace(s)
+
+	if strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}") && strings.Count(trimmed, "{{") == 1 {
+		expr, err := parseTemplateExpr(trimmed)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(ctx *templateCtx) (interface{}, bool) {
+			v, ok := expr.eval(ctx)
+			if !ok {
+				return nil, true
+			}
+			return cloneValue(v.GetAsInterface()), true
+		}, nil
+	}
+
+	if !strings.Contains(s, "{{") {
+		return func(ctx *templateCtx) (interface{}, bool) {
+			return s, true
+		}, nil
+	}
+
+	lits := make([]string, 0)
+	exprs := make([]*templateExpr, 0)
+
+	rest := s
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			lits = append(lits, rest)
+			break
+		}
+
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed {{ in %s", invalidTemplateError, s)
+		}
+
+		expr, err := parseTemplateExpr(rest[start : start+end+2])
+		if err != nil {
+			return nil, err
+		}
+
+		lits = append(lits, rest[:start])
+		exprs = append(exprs, expr)
+		rest = rest[start+end+2:]
+	}
+
+	return func(ctx *templateCtx) (interface{}, bool) {
+		var sb strings.Builder
+
+		for idx := range exprs {
+			sb.WriteString(lits[idx])
+			if v, ok := exprs[idx].eval(ctx); ok && !v.IsNull() {
+				sb.WriteString(v.ToString())
+			}
+		}
+		sb.WriteString(lits[len(lits)-1])
+
+		return sb.String(), true
+	}, nil
+}
+
+// parseTemplateExpr parses `path | filter | filter(arg)` with or without {{ }}.
+
+func parseTemplateExpr(s string) (*templateExpr, error) {
+	src := strings.TrimSpace(s)
+	if strings.HasPrefix(src, "{{") && strings.HasSuffix(src, "}}") {
+		src = strings.TrimSpace(src[2 : len(src)-2])
+	}
+
+	parts := splitTemplateFilters(src)
+	path := strings.TrimSpace(parts[0])
+
+	m := &templateExpr{scope: templateScopeCurrent}
+
+	switch {
+	case path == "$index":
+		m.scope = templateScopeIndex
+		path = ""
+	case strings.HasPrefix(path, "$root"):
+		m.scope = templateScopeRoot
+		path = strings.TrimSpace(path[len("$root"):])
+	case path == ".":
+		path = ""
+	}
+
+	if path != "" {
+		m.token = PathTokenizer(path)
+		if len(m.token) == 0 {
+			return nil, fmt.Errorf("%w: invalid path %s", invalidTemplateError, path)
+		}
+	}
+
+	for _, part := range parts[1:] {
+		f := strings.TrimSpace(part)
+
+		name := f
+		var arg interface{}
+
+		if open := strings.IndexByte(f, '('); open >= 0 && strings.HasSuffix(f, ")") {
+			name = strings.TrimSpace(f[:open])
+
+			v, err := decodeDocument([]byte(strings.TrimSpace(f[open+1:len(f)-1])), &ParseOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid argument of %s: %s", invalidTemplateError, name, err.Error())
+			}
+			arg = v
+		}
+
+		switch name {
+		case "default":
+			if !strings.Contains(f, "(") {
+				return nil, fmt.Errorf("%w: default needs a value", invalidTemplateError)
+			}
+		case "int", "float", "string", "bool":
+			if strings.Contains(f, "(") {
+				return nil, fmt.Errorf("%w: %s takes no argument", invalidTemplateError, name)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown filter %s", invalidTemplateError, name)
+		}
+
+		m.filters = append(m.filters, templateFilter{name: name, arg: arg})
+	}
+
+	return m, nil
+}
+
+// splitTemplateFilters splits on | outside of brackets and quotes.
+
+func splitTemplateFilters(s string) []string {
+	parts := make([]string, 0)
+
+	depth := 0
+	var quote rune
+	prev := rune(0)
+	start := 0
+
+	for idx, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote && prev != '\\' {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '(':
+			depth++
+		case r == ']' || r == ')':
+			depth--
+		case r == '|' && depth == 0:
+			parts = append(parts, s[start:idx])
+			start = idx + 1
+		}
+		prev = r
+	}
+
+	return append(parts, s[start:])
+}
+
+func (m *templateExpr) eval(ctx *templateCtx) (*DJSON, bool) {
+	var v *DJSON
+	ok := true
+
+	switch m.scope {
+	case templateScopeRoot:
+		v, ok = ctx.root.getByTokens(m.token...)
+	case templateScopeIndex:
+		v = NewDJSON().Put(int64(ctx.index))
+	default:
+		v, ok = ctx.cur.getByTokens(m.token...)
+	}
+
+	for _, f := range m.filters {
+		switch f.name {
+		case "default":
+			if !ok || v.IsNull() {
+				v, ok = NewDJSON().Put(cloneValue(f.arg)), true
+			}
+		case "int":
+			if ok {
+				v = NewDJSON().Put(v.GetAsInt())
+			}
+		case "float":
+			if ok {
+				v = NewDJSON().Put(v.GetAsFloat())
+			}
+		case "string":
+			if ok {
+				v = NewDJSON().Put(v.ToString())
+			}
+		case "bool":
+			if ok {
+				v = NewDJSON().Put(v.GetAsBool())
+			}
+		}
+	}
+
+	return v, ok
+}
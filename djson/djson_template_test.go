@@ -0,0 +1,71 @@
+package djson
+
+import (
+	"log"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	src := NewDJSON().Parse(`{
+		"order": {"id": "A-1", "total": "30"},
+		"customer": {"last": "Hong", "first": "Gil-dong", "vip": true},
+		"items": [{"sku": "x1", "qty": 2}, {"sku": "y2"}]
+	}`)
+
+	tmpl, err := ParseTemplate(`{
+		"orderNo": "{{ [\"order\"][\"id\"] }}",
+		"amount": "{{ [\"order\"][\"total\"] | int }}",
+		"currency": "{{ [\"order\"][\"currency\"] | default(\"KRW\") }}",
+		"name": "{{ [\"customer\"][\"last\"] }} {{ [\"customer\"][\"first\"] }}",
+		"lines": {
+			"$each": "[\"items\"]",
+			"$do": {
+				"no": "{{ $index }}",
+				"order": "{{ $root[\"order\"][\"id\"] }}",
+				"code": "{{ [\"sku\"] }}",
+				"count": "{{ [\"qty\"] | default(1) }}"
+			}
+		},
+		"tier": {"$if": "[\"customer\"][\"vip\"]", "$then": "gold", "$else": "normal"},
+		"memo": {"$unless": "[\"customer\"][\"vip\"]", "$then": "plain"}
+	}`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ret := tmpl.Render(src)
+	log.Println(ret.ToString())
+
+	expected := `{"amount":30,"currency":"KRW","lines":[{"code":"x1","count":2,"no":0,"order":"A-1"},{"code":"y2","count":1,"no":1,"order":"A-1"}],"name":"Hong Gil-dong","orderNo":"A-1","tier":"gold"}`
+	if out := ret.ToStringWith(SerializeOptions{SortKeys: true}); out != expected {
+		log.Fatal("expected ", expected, " but ", out)
+	}
+
+	// a template is reusable and renders nothing for a missing array
+	ret = tmpl.Render(NewDJSON().Parse(`{"customer": {"vip": false}}`))
+	lines, _ := ret.GetAsArray("lines")
+	if ret.GetAsString("tier") != "normal" || ret.GetAsString("memo") != "plain" || lines.Length() != 0 {
+		log.Fatal("render failed: ", ret.ToString())
+	}
+
+	if !ret.HasKey("orderNo") || !ret.IsNull("orderNo") {
+		log.Fatal("missing value must be null")
+	}
+}
+
+func TestTemplateError(t *testing.T) {
+	for _, doc := range []string{
+		`{"a": "{{ [\"x\"] | upper }}"}`,
+		`{"a": {"$loop": "[\"x\"]"}}`,
+		`{"a": "text {{ [\"x\"]"}`,
+		`{"a": {"$each": "[\"x\"]", "$then": 1}}`,
+		`{"a": "{{ [\"x\"] | default }}"}`,
+		`{"a": 1`,
+	} {
+		if _, err := ParseTemplate(doc); err == nil {
+			log.Fatal("must not compile: ", doc)
+		} else {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package djson
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// encodings of GetAsBytes, as ByteBuilder
+
+const (
+	BYTES_BASE64 int = iota
+	BYTES_HEX
+	BYTES_BASE58
+)
+
+// layouts of GetAsTime tried after the given layouts
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"15:04:05",
+}
+
+// GetAsTime returns a time from RFC3339, unix seconds or millis (number or
+// digits), YYYYMMDD, HHMMSS, YYYYMMDDHHMMSS or one of layouts. Times without
+// a zone are UTC.
+
+func (m *DJSON) GetAsTime(key interface{}, layouts ...string) (time.Time, bool) {
+	v, ok := m.Get(key)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	switch v.JsonType {
+	case JSON_INT:
+		return unixToTime(float64(v.Int)), true
+	case JSON_FLOAT:
+		return unixToTime(v.Float), true
+	case JSON_STRING:
+		return parseTime(strings.TrimSpace(v.String), layouts)
+	}
+
+	return time.Time{}, false
+}
+
+func parseTime(s string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	if s != "" && strings.Trim(s, "0123456789") == "" {
+		var layout string
+
+		switch len(s) {
+		case 6:
+			layout = "150405"
+		case 8:
+			layout = "20060102"
+		case 14:
+			layout = "20060102150405"
+		case 9, 10, 11, 12, 13:
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return unixToTime(float64(i)), true
+		default:
+			return time.Time{}, false
+		}
+
+		t, err := time.Parse(layout, s)
+		return t, err == nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// unixToTime takes seconds, or millis when beyond year 5138.
+
+func unixToTime(f float64) time.Time {
+	if math.Abs(f) >= 1e11 {
+		ms := math.Floor(f)
+		return time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC()
+	}
+
+	sec := math.Floor(f)
+	return time.Unix(int64(sec), int64((f-sec)*1e9)).UTC()
+}
+
+// GetAsDuration returns a duration from a Go duration string ("1h30m") or
+// from seconds as a number or a numeric string.
+
+func (m *DJSON) GetAsDuration(key ...interface{}) (time.Duration, bool) {
+	v, ok := m.Get(key...)
+	if !ok {
+		return 0, false
+	}
+
+	switch v.JsonType {
+	case JSON_INT:
+		return time.Duration(v.Int) * time.Second, true
+	case JSON_FLOAT:
+		return time.Duration(v.Float * float64(time.Second)), true
+	case JSON_STRING:
+		s := strings.TrimSpace(v.String)
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.Duration(f * float64(time.Second)), true
+		}
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
+
+// GetAsBytes decodes a string in encoding (BYTES_BASE64 by default, the
+// format Put writes []byte in). Hex may have the 0x prefix.
+
+func (m *DJSON) GetAsBytes(key interface{}, encoding ...int) ([]byte, bool) {
+	v, ok := m.Get(key)
+	if !ok || !v.IsString() {
+		return nil, false
+	}
+
+	enc := BYTES_BASE64
+	if len(encoding) > 0 {
+		enc = encoding[0]
+	}
+
+	switch enc {
+	case BYTES_BASE64:
+		if data, err := base64.StdEncoding.DecodeString(v.String); err == nil {
+			return data, true
+		}
+	case BYTES_HEX:
+		if data, err := hex.DecodeString(strings.TrimPrefix(v.String, "0x")); err == nil {
+			return data, true
+		}
+	case BYTES_BASE58:
+		data := base58.Decode(v.String)
+		if len(data) > 0 || v.String == "" {
+			return data, true
+		}
+	}
+
+	return nil, false
+}
+
+// GetAsStringSlice returns the elements of an array as GetAsString does.
+
+func (m *DJSON) GetAsStringSlice(key ...interface{}) ([]string, bool) {
+	v, ok := m.Get(key...)
+	if !ok || !v.IsArray() {
+		return nil, false
+	}
+
+	ret := make([]string, v.Array.Size())
+	for idx := range ret {
+		ret[idx] = v.Array.GetAsString(idx)
+	}
+
+	return ret, true
+}
+
+// GetAsIntSlice returns the elements of an array as integers. It fails if
+// any element is not a number or a numeric string.
+
+func (m *DJSON) GetAsIntSlice(key ...interface{}) ([]int64, bool) {
+	v, ok := m.Get(key...)
+	if !ok || !v.IsArray() {
+		return nil, false
+	}
+
+	ret := make([]int64, v.Array.Size())
+	for idx := range ret {
+		if ret[idx], ok = v.Array.GetAsInt(idx); !ok {
+			return nil, false
+		}
+	}
+
+	return ret, true
+}
@@ -0,0 +1,106 @@
+package djson
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestGetAsTime(t *testing.T) {
+	aJson := NewDJSON().Parse(`{
+		"rfc": "2024-03-01T09:30:00+09:00",
+		"unix": 1709253000,
+		"millis": 1709253000123,
+		"unixStr": "1709253000",
+		"date": "20240301",
+		"clock": "093000",
+		"custom": "01/03/2024",
+		"bad": "tomorrow",
+		"ttl": "1h30m",
+		"timeout": 2.5,
+		"sig": "3q2+7w==",
+		"hex": "0xdeadbeef",
+		"b58": "6h8cQN",
+		"names": ["a", 1, true],
+		"ids": [1, "2", 3],
+		"mixed": [1, "x"]
+	}`)
+
+	want := time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)
+
+	cases := map[string]time.Time{
+		"rfc":     want,
+		"unix":    want,
+		"millis":  want.Add(123 * time.Millisecond),
+		"unixStr": want,
+		"date":    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"clock":   time.Date(0, 1, 1, 9, 30, 0, 0, time.UTC),
+	}
+
+	for key, expected := range cases {
+		if tm, ok := aJson.GetAsTime(key); !ok || !tm.Equal(expected) {
+			log.Fatal(key, ": expected ", expected, " but ", tm)
+		}
+	}
+
+	if tm, ok := aJson.GetAsTime("custom", "02/01/2006"); !ok || tm.Month() != time.March {
+		log.Fatal("layout must be used")
+	}
+
+	if _, ok := aJson.GetAsTime("bad"); ok {
+		log.Fatal("must not be a time")
+	}
+
+	if d, ok := aJson.GetAsDuration("ttl"); !ok || d != 90*time.Minute {
+		log.Fatal("wrong duration")
+	}
+
+	if d, ok := aJson.GetAsDurationPath(`["timeout"]`); !ok || d != 2500*time.Millisecond {
+		log.Fatal("wrong duration")
+	}
+
+	if b, ok := aJson.GetAsBytes("sig"); !ok || !bytes.Equal(b, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		log.Fatal("wrong base64")
+	}
+
+	if b, ok := aJson.GetAsBytesPath(`["hex"]`, BYTES_HEX); !ok || !bytes.Equal(b, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		log.Fatal("wrong hex")
+	}
+
+	if b, ok := aJson.GetAsBytes("b58", BYTES_BASE58); !ok || !bytes.Equal(b, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		log.Fatal("wrong base58: ", b)
+	}
+
+	if s, ok := aJson.GetAsStringSlice("names"); !ok || len(s) != 3 || s[2] != "true" {
+		log.Fatal("wrong string slice")
+	}
+
+	if s, ok := aJson.GetAsIntSlicePath(`["ids"]`); !ok || s[1] != 2 {
+		log.Fatal("wrong int slice")
+	}
+
+	if _, ok := aJson.GetAsIntSlice("mixed"); ok {
+		log.Fatal("must fail with a non-numeric element")
+	}
+}
+
+func TestPutTimeAndBytes(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 30, 0, 500, time.FixedZone("KST", 9*3600))
+
+	aJson := NewDJSON().Put("at", at).Put("sig", []byte{0xde, 0xad, 0xbe, 0xef})
+	aJson.Put("list", NewDJSON().PutAsArray(at))
+
+	expected := `{"at":"2024-03-01T09:30:00.0000005+09:00","list":["2024-03-01T09:30:00.0000005+09:00"],"sig":"3q2+7w=="}`
+	if aJson.ToString() != expected {
+		log.Fatal("expected ", expected, " but ", aJson.ToString())
+	}
+
+	if tm, ok := aJson.GetAsTime("at"); !ok || !tm.Equal(at) {
+		log.Fatal("time must round-trip")
+	}
+
+	if NewDJSON().Put(at).ToString() != "2024-03-01T09:30:00.0000005+09:00" {
+		log.Fatal("time must be a string")
+	}
+}
@@ -368,6 +368,10 @@ func (m *DJSON) fromFieldsValue(val reflect.Value, tags ...string) {
 }
 
 func (m *DJSON) FromFields(st interface{}, tags ...string) *DJSON {
+	if m.frozen {
+		return m
+	}
+
 	baseValue := reflect.ValueOf(st)
 
 	kind := baseValue.Type().Kind()
@@ -416,6 +420,10 @@ func inTags(idv string, tags ...string) bool {
 func (m *DJSON) doSort(isAsc bool, k ...interface{}) bool {
 	var tArray *DA
 
+	if m.frozen {
+		return false
+	}
+
 	if len(k) == 0 {
 		if m.JsonType == JSON_ARRAY {
 			tArray = m.Array
@@ -455,7 +463,7 @@ func (m *DJSON) SortDesc(k ...interface{}) bool {
 }
 
 func (m *DJSON) SortObjectArray(isAsc bool, key string) bool {
-	if m.JsonType != JSON_ARRAY {
+	if m.JsonType != JSON_ARRAY || m.frozen {
 		return false
 	}
 
@@ -535,11 +543,7 @@ func (m *DJSON) GetKeys(k ...interface{}) []string {
 			return rk
 		}
 
-		for k := range m.Object.Map {
-			rk = append(rk, k)
-		}
-
-		return rk
+		return m.Object.Keys()
 	}
 
 	if t, ok := m.GetAsObject(k[0]); ok {
@@ -569,7 +573,7 @@ func (m *DJSON) Find(key string, val string) *DJSON {
 }
 
 func (m *DJSON) Append(arrJson *DJSON) *DJSON {
-	if arrJson == nil || m.JsonType != JSON_ARRAY || !arrJson.IsArray() {
+	if arrJson == nil || m.JsonType != JSON_ARRAY || !arrJson.IsArray() || m.frozen {
 		return m
 	}
 
@@ -0,0 +1,238 @@
+package djson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Change describes a modification made through Put, Remove, ReplaceAt,
+// UpdatePath or RemovePath. Old is nil when the value did not exist before,
+// New is nil when the value was removed. Path is empty for the whole document.
+// Old and New may share values with the document; clone them to keep them.
+
+type Change struct {
+	Path string
+	Old  *DJSON
+	New  *DJSON
+}
+
+type watcher struct {
+	id       int
+	token    []interface{}
+	callback func(c Change)
+}
+
+type watcherSet struct {
+	lastId int
+	list   []*watcher
+}
+
+// Watch registers callback for changes at path, below path (subtree) or above
+// path (ancestor replaced). An empty path watches the whole document.
+// Only changes made through this DJSON are reported; a DJSON returned by
+// GetAsObject or GetAsArray shares data but not watchers.
+
+func (m *DJSON) Watch(path string, callback func(c Change)) int {
+	if m.watchers == nil {
+		m.watchers = &watcherSet{}
+	}
+
+	m.watchers.lastId++
+	m.watchers.list = append(m.watchers.list, &watcher{
+		id:       m.watchers.lastId,
+		token:    PathTokenizer(path),
+		callback: callback,
+	})
+
+	return m.watchers.lastId
+}
+
+func (m *DJSON) Unwatch(id int) bool {
+	if m.watchers == nil {
+		return false
+	}
+
+	for idx := range m.watchers.list {
+		if m.watchers.list[idx].id == id {
+			m.watchers.list = append(m.watchers.list[:idx], m.watchers.list[idx+1:]...)
+			if len(m.watchers.list) == 0 {
+				m.watchers = nil
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// BuildPath is the inverse of PathTokenizer, e.g. ["user"][0]["name"]
+
+func BuildPath(token ...interface{}) string {
+	var sb strings.Builder
+
+	for idx := range token {
+		switch t := token[idx].(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(t) + "]")
+		default:
+			sb.WriteString(`["` + fmt.Sprint(t) + `"]`)
+		}
+	}
+
+	return sb.String()
+}
+
+func (m *DJSON) putWatched(v ...interface{}) *DJSON {
+	var done func(bool)
+
+	if key, ok := v[0].(string); ok && len(v) == 2 && (m.JsonType == JSON_OBJECT || m.JsonType == JSON_NULL) {
+		done = m.trackChange(key)
+	} else {
+		done = m.trackChange()
+	}
+
+	r := m.put(v...)
+	done(true)
+
+	return r
+}
+
+// trackChange captures the value at token before a mutation. The returned
+// function must be called after the mutation and notifies matching watchers.
+
+func (m *DJSON) trackChange(token ...interface{}) func(bool) {
+	return m.track(false, token)
+}
+
+// trackRemoval is trackChange for a removal, whose New is nil; the index of
+// a removed element holds the next one after the mutation.
+
+func (m *DJSON) trackRemoval(token ...interface{}) func(bool) {
+	return m.track(true, token)
+}
+
+func (m *DJSON) track(removal bool, token []interface{}) func(bool) {
+	if !m.isWatched(token) {
+		return func(bool) {}
+	}
+
+	var oldJson *DJSON
+	var oldOk bool
+
+	if len(token) == 0 {
+		oldJson, oldOk = m.snapshot(), true
+	} else {
+		oldJson, oldOk = m.getByTokens(token...)
+	}
+
+	return func(applied bool) {
+		if !applied || m.watchers == nil {
+			return
+		}
+
+		var newJson *DJSON
+		var newOk bool
+
+		if !removal {
+			newJson, newOk = m.getByTokens(token...)
+		}
+
+		if !oldOk && !newOk {
+			return
+		}
+
+		c := Change{
+			Path: BuildPath(token...),
+		}
+
+		if oldOk {
+			c.Old = oldJson
+		}
+
+		if newOk {
+			c.New = newJson
+		}
+
+		for _, w := range m.watchers.list {
+			if isRelatedPath(w.token, token) {
+				w.callback(c)
+			}
+		}
+	}
+}
+
+func (m *DJSON) isWatched(token []interface{}) bool {
+	if m.watchers == nil {
+		return false
+	}
+
+	for _, w := range m.watchers.list {
+		if isRelatedPath(w.token, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// snapshot copies m one level deep, which is all a change of the root
+// replaces; the values below are shared.
+
+func (m *DJSON) snapshot() *DJSON {
+	ret := &DJSON{
+		String:   m.String,
+		Int:      m.Int,
+		Float:    m.Float,
+		Bool:     m.Bool,
+		JsonType: m.JsonType,
+	}
+
+	switch m.JsonType {
+	case JSON_OBJECT:
+		m.Object.load()
+		ret.Object = &DO{
+			Map:  make(map[string]interface{}, len(m.Object.Map)),
+			keys: append([]string(nil), m.Object.keys...),
+		}
+		for k, v := range m.Object.Map {
+			ret.Object.Map[k] = v
+		}
+	case JSON_ARRAY:
+		m.Array.load()
+		ret.Array = &DA{
+			Element: append([]interface{}(nil), m.Array.Element...),
+		}
+	}
+
+	return ret
+}
+
+func (m *DJSON) getByTokens(token ...interface{}) (*DJSON, bool) {
+	cur := m
+
+	for idx := range token {
+		next, ok := cur.Get(token[idx])
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+
+	return cur, true
+}
+
+func isRelatedPath(a, b []interface{}) bool {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+
+	for idx := 0; idx < n; idx++ {
+		if a[idx] != b[idx] { // index 0 is not key "0"
+			return false
+		}
+	}
+
+	return true
+}
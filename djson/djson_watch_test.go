@@ -0,0 +1,114 @@
+package djson
+
+import (
+	"log"
+	"testing"
+)
+
+func TestWatchPath(t *testing.T) {
+	aJson := NewDJSON().Parse(`{"server":{"port":8080,"host":"localhost"},"debug":false}`)
+
+	changes := make([]Change, 0)
+
+	id := aJson.Watch(`["server"]`, func(c Change) {
+		log.Println(c.Path, c.Old, c.New)
+		changes = append(changes, c)
+	})
+
+	_ = aJson.UpdatePath(`["server"]["port"]`, 9090)
+	aJson.Put("debug", true) // not watched
+	_ = aJson.RemovePath(`["server"]["host"]`)
+
+	if len(changes) != 2 {
+		log.Fatal("watcher must be called twice")
+	}
+
+	if changes[0].Path != `["server"]["port"]` || changes[0].Old.GetAsInt() != 8080 || changes[0].New.GetAsInt() != 9090 {
+		log.Fatal("wrong change for port")
+	}
+
+	if changes[1].New != nil || changes[1].Old.GetAsString() != "localhost" {
+		log.Fatal("wrong change for host")
+	}
+
+	aJson.Put("server", Object{"port": 80}) // ancestor replaced
+	if len(changes) != 3 || changes[2].Path != `["server"]` {
+		log.Fatal("ancestor change not reported")
+	}
+
+	aJson.Unwatch(id)
+	aJson.Put("server", nil)
+	if len(changes) != 3 {
+		log.Fatal("Unwatch() failed")
+	}
+}
+
+func TestWatchRoot(t *testing.T) {
+	aJson := NewDJSON().Parse(`{"name":"Ricardo Longa"}`)
+
+	var last Change
+	aJson.Watch("", func(c Change) {
+		last = c
+	})
+
+	aJson.Put(Object{"idade": 28})
+
+	if last.Path != "" || last.Old.HasKey("idade") || !last.New.HasKey("idade") {
+		log.Fatal("root change not reported")
+	}
+
+	aJson.ReplaceAt("name", "Hery Victor")
+
+	if last.Path != `["name"]` || last.New.GetAsString() != "Hery Victor" {
+		log.Fatal("ReplaceAt() change not reported")
+	}
+}
+
+func TestWatchRemove(t *testing.T) {
+	aJson := NewDJSON().Parse(`{"list":["a","b","c"],"0":"zero"}`)
+
+	changes := make([]Change, 0)
+	aJson.Watch(`["list"][1]`, func(c Change) {
+		changes = append(changes, c)
+	})
+
+	list, _ := aJson.Get("list")
+	list.Watch("[1]", func(c Change) {
+		changes = append(changes, c)
+	})
+
+	list.Remove(1)
+	_ = aJson.RemovePath(`["list"][1]`)
+
+	if len(changes) != 2 {
+		log.Fatal("watchers must be called twice")
+	}
+
+	if changes[0].Old.GetAsString() != "b" || changes[0].New != nil {
+		log.Fatal("Remove() must report the removed element")
+	}
+
+	if changes[1].Old.GetAsString() != "c" || changes[1].New != nil {
+		log.Fatal("RemovePath() must report the removed element")
+	}
+
+	aJson.Watch("[0]", func(c Change) {
+		log.Fatal("key \"0\" is not index 0")
+	})
+	aJson.Put("0", "ZERO")
+}
+
+func TestWatchRootSnapshot(t *testing.T) {
+	aJson := NewDJSON().Parse(`[1,2]`)
+
+	var last Change
+	aJson.Watch("", func(c Change) {
+		last = c
+	})
+
+	aJson.Put(3, 4, 5)
+
+	if last.Old.Length() != 2 || last.New.Length() != 5 {
+		log.Fatal("root change must keep the old elements")
+	}
+}
@@ -5,3 +5,12 @@ import "errors"
 var invalidPathError = errors.New("invalid path")
 var unavailableError = errors.New("path func unavailable")
 var failedToSortError = errors.New("failedToSortError")
+var frozenError = errors.New("frozen document")
+var notFrozenError = errors.New("document not frozen")
+var invalidRedactRuleError = errors.New("invalid redact rule")
+var invalidRedactActionError = errors.New("invalid redact action")
+var invalidPatternError = errors.New("invalid path pattern")
+var invalidQueryError = errors.New("invalid query")
+var invalidTemplateError = errors.New("invalid template")
+var invalidFormatNameError = errors.New("invalid format name")
+var formatExistsError = errors.New("format already registered")
@@ -1,15 +1,23 @@
 package djson
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"math"
 	"reflect"
+	"sort"
+	"time"
 
 	"github.com/volatiletech/null/v8"
 )
 
+// DO is a JSON object. Map is empty until a lazily parsed object is loaded
+// by a method; see ParseLazy.
+
 type DO struct {
-	Map map[string]interface{}
+	Map  map[string]interface{}
+	keys []string // insertion order
+	lazy *lazyRaw // not yet decoded, see ParseLazy
 }
 
 func NewObject() *DO {
@@ -19,6 +27,20 @@ func NewObject() *DO {
 }
 
 func (m *DO) Put(key string, value interface{}) *DO {
+	m.load()
+
+	isNew := !m.HasKey(key)
+
+	m.put(key, value)
+
+	if isNew && m.HasKey(key) {
+		m.keys = append(m.keys, key)
+	}
+
+	return m
+}
+
+func (m *DO) put(key string, value interface{}) *DO {
 
 	if IsFloatType(value) {
 		switch t := value.(type) {
@@ -97,6 +119,10 @@ func (m *DO) Put(key string, value interface{}) *DO {
 		m.Map[key] = ConverMapToObject(t)
 	case Array:
 		m.Map[key] = ConvertSliceToArray(t)
+	case time.Time:
+		m.Map[key] = t.Format(time.RFC3339Nano)
+	case []byte:
+		m.Map[key] = base64.StdEncoding.EncodeToString(t)
 	case DJSON:
 		m.Map[key] = t.GetAsInterface()
 	case *DJSON:
@@ -124,6 +150,7 @@ func (m *DO) Append(obj map[string]interface{}) *DO {
 }
 
 func (m *DO) HasKey(key string) bool {
+	m.load()
 	_, ok := m.Map[key]
 	return ok
 }
@@ -133,6 +160,8 @@ func (m *DO) GetAsString(key string) string {
 		return ""
 	}
 
+	m.load()
+
 	value, ok := m.Map[key]
 	if !ok {
 		return ""
@@ -159,6 +188,7 @@ func (m *DO) GetAsString(key string) string {
 }
 
 func (m *DO) GetAsString2(key string) (string, bool) {
+	m.load()
 	value, ok := m.Map[key]
 	if !ok {
 		return "", false
@@ -181,6 +211,7 @@ func (m *DO) GetAsString2(key string) (string, bool) {
 }
 
 func (m *DO) Get(key string) (interface{}, bool) {
+	m.load()
 	value, ok := m.Map[key]
 	if !ok {
 		return nil, false
@@ -190,6 +221,7 @@ func (m *DO) Get(key string) (interface{}, bool) {
 }
 
 func (m *DO) GetType(key string) (string, bool) {
+	m.load()
 	value, ok := m.Map[key]
 	if !ok {
 		return "", false
@@ -216,6 +248,7 @@ func (m *DO) GetType(key string) (string, bool) {
 }
 
 func (m *DO) GetAsBool(key string) (bool, bool) {
+	m.load()
 	value, ok := m.Map[key]
 	if !ok {
 		return false, false
@@ -229,6 +262,7 @@ func (m *DO) GetAsBool(key string) (bool, bool) {
 }
 
 func (m *DO) GetAsFloat(key string) (float64, bool) {
+	m.load()
 	value, ok := m.Map[key]
 	if !ok {
 		return 0, false
@@ -242,6 +276,7 @@ func (m *DO) GetAsFloat(key string) (float64, bool) {
 }
 
 func (m *DO) GetAsInt(key string) (int64, bool) {
+	m.load()
 	value, ok := m.Map[key]
 	if !ok {
 		return 0, false
@@ -255,6 +290,7 @@ func (m *DO) GetAsInt(key string) (int64, bool) {
 }
 
 func (m *DO) GetAsObject(key string) (*DO, bool) {
+	m.load()
 	value, ok := m.Map[key]
 	if !ok {
 		return nil, false
@@ -264,8 +300,10 @@ func (m *DO) GetAsObject(key string) (*DO, bool) {
 	case DO:
 		return &t, true
 	case *DO:
+		t.load()
 		return t, true
 	case **DO:
+		(*t).load()
 		return *t, true
 	}
 
@@ -273,6 +311,7 @@ func (m *DO) GetAsObject(key string) (*DO, bool) {
 }
 
 func (m *DO) GetAsArray(key string) (*DA, bool) {
+	m.load()
 
 	value, ok := m.Map[key]
 	if !ok {
@@ -283,8 +322,10 @@ func (m *DO) GetAsArray(key string) (*DA, bool) {
 	case DA:
 		return &t, true
 	case *DA:
+		t.load()
 		return t, true
 	case **DA:
+		(*t).load()
 		return *t, true
 	}
 
@@ -293,31 +334,72 @@ func (m *DO) GetAsArray(key string) (*DA, bool) {
 }
 
 func (m *DO) Remove(keys ...string) *DO {
+	m.load()
+
 	for idx := range keys {
+		if !m.HasKey(keys[idx]) {
+			continue
+		}
+
 		delete(m.Map, keys[idx])
+
+		for kdx := range m.keys {
+			if m.keys[kdx] == keys[idx] {
+				m.keys = append(m.keys[:kdx], m.keys[kdx+1:]...)
+				break
+			}
+		}
 	}
 	return m
 }
 
+// Keys returns keys in insertion order. Keys written to Map directly
+// come last in sorted order.
+
+func (m *DO) Keys() []string {
+	m.load()
+
+	rk := make([]string, 0, len(m.Map))
+	seen := make(map[string]bool, len(m.Map))
+
+	for _, k := range m.keys {
+		if _, ok := m.Map[k]; ok && !seen[k] {
+			rk = append(rk, k)
+			seen[k] = true
+		}
+	}
+
+	if len(rk) == len(m.Map) {
+		return rk
+	}
+
+	rest := make([]string, 0)
+	for k := range m.Map {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+
+	sort.Strings(rest)
+
+	return append(rk, rest...)
+}
+
 func (m *DO) ToStringPretty() string {
-	jsonByte, _ := json.MarshalIndent(ConverObjectToMap(m), "", "   ")
-	return string(jsonByte)
+	return marshalString(m, toStringPrettyOptions)
 }
 
 func (m *DO) ToString() string {
-	jsonByte, err := json.Marshal(ConverObjectToMap(m))
-	if err != nil {
-		// log.Println(err)
-		return ""
-	}
-	return string(jsonByte)
+	return marshalString(m, toStringOptions)
 }
 
 func (m *DO) Length() int {
+	m.load()
 	return len(m.Map)
 }
 
 func (m *DO) Size() int {
+	m.load()
 	return len(m.Map)
 }
 
@@ -391,10 +473,14 @@ func (m *DO) Equal(t *DO) bool {
 }
 
 func (m *DO) Clone() *DO {
+	if m.lazy != nil { // raw bytes are never modified
+		return &DO{lazy: m.lazy}
+	}
 
 	t := NewObject()
 
 	t.Map = make(map[string]interface{})
+	t.keys = m.Keys()
 
 	for k := range m.Map {
 
@@ -412,7 +498,7 @@ func (m *DO) Clone() *DO {
 			t.Map[k] = m.Map[k].(bool)
 		case "int", "uint", "int8", "uint8", "int16", "uint16", "int32", "uint32", "int64", "uint64":
 			t.Map[k], _ = m.GetAsInt(k)
-		case "Map", "float64":
+		case "float32", "float64":
 			t.Map[k], _ = m.GetAsFloat(k)
 		case "*djson.DO":
 			mdo := m.Map[k].(*DO)
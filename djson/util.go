@@ -28,6 +28,7 @@ func ConvertSliceToArray(dslice []interface{}) *DA {
 func ConverObjectToMap(obj *DO) map[string]interface{} {
 	wMap := make(map[string]interface{})
 
+	obj.load()
 	for k, v := range obj.Map {
 		switch t := v.(type) {
 		case DA:
@@ -50,6 +51,7 @@ func ConvertArrayToSlice(arr *DA) []interface{} {
 
 	wArray := make([]interface{}, 0)
 
+	arr.load()
 	for idx := range arr.Element {
 		switch t := arr.Element[idx].(type) {
 		case DA:
@@ -164,31 +166,35 @@ func IsInTypes(v interface{}, types ...string) bool {
 	return false
 }
 
-func ParseToObject(doc string) (*DO, error) {
-	var data map[string]interface{}
+// ParseToObject and ParseToArray keep the key order of doc.
 
-	d := json.NewDecoder(strings.NewReader(doc))
-	d.UseNumber()
+func ParseToObject(doc string) (*DO, error) {
+	v, err := decodeFirst([]byte(doc), &ParseOptions{})
+	if err != nil {
+		return nil, errors.New("not Object")
+	}
 
-	if err := d.Decode(&data); err != nil {
+	obj, ok := v.(*DO)
+	if !ok {
 		return nil, errors.New("not Object")
 	}
 
-	return ParseObject(data), nil
+	return obj, nil
 
 }
 
 func ParseToArray(doc string) (*DA, error) {
-	var data []interface{}
-
-	d := json.NewDecoder(strings.NewReader(doc))
-	d.UseNumber()
+	v, err := decodeFirst([]byte(doc), &ParseOptions{})
+	if err != nil {
+		return nil, errors.New("not Array")
+	}
 
-	if err := d.Decode(&data); err != nil {
+	arr, ok := v.(*DA)
+	if !ok {
 		return nil, errors.New("not Array")
 	}
 
-	return ParseArray(data), nil
+	return arr, nil
 }
 
 func ParseObject(data map[string]interface{}) *DO {
@@ -260,6 +266,18 @@ func ParseArray(data []interface{}) *DA {
 	return arr
 }
 
+// keyOrPathTokens tokenizes a path (`["a"][0]`), taking anything else but
+// "" as a plain key.
+
+func keyOrPathTokens(path string) []interface{} {
+	tokens := PathTokenizer(path)
+	if len(tokens) == 0 && path != "" {
+		return []interface{}{path}
+	}
+
+	return tokens
+}
+
 func PathTokenizer(path string) []interface{} {
 	rstack := NewRuneStack()
 	token := make([]rune, 0)
@@ -17,6 +17,8 @@ const (
 	V_TYPE_OBJECT
 	V_TYPE_ARRAY
 	V_TYPE_MULTI
+	V_TYPE_REF
+	V_TYPE_NIL // "NULL", only null; V_TYPE_NULL is no type
 )
 
 var CountryCodes = []string{
@@ -46,6 +48,11 @@ var TelRegExp *regexp.Regexp
 var BinRegExp *regexp.Regexp
 var DecRegExp *regexp.Regexp
 
+// NoneRegExp matches no string; it stands for a "regexp" which does not
+// compile, so the value is rejected rather than left unchecked.
+
+var NoneRegExp = regexp.MustCompile(`[^\x00-\x{10FFFF}]`)
+
 func CheckFuncHex(ts string, vi ...int64) bool {
 	return HexRegExp.Match([]byte(ts))
 }
@@ -169,22 +176,53 @@ func init() {
 }
 
 type VItem struct {
-	Type      int
-	Name      string
-	Max       int64
-	Min       int64
-	MaxFloat  float64
-	MinFloat  float64
-	Size      int64
-	IsRequred bool
-	SubItems  []*VItem
-	CheckFunc func(string, ...int64) bool
-	RegExp    *regexp.Regexp
+	Type       int
+	Name       string
+	Max        int64
+	Min        int64
+	MaxFloat   float64
+	MinFloat   float64
+	Size       int64
+	IsRequred  bool
+	SubItems   []*VItem
+	CheckFunc  func(string, ...int64) bool
+	RegExp     *regexp.Regexp
+	Default    *DJSON        // "default", filled by Normalize when absent
+	TypeName   string        // type in the syntax, e.g. "EMAIL"
+	Enum       []interface{} // "enum" or "const", the allowed values
+	IgnoreCase bool          // string values of Enum match in any case
+	RefName    string        // "ref" of V_TYPE_REF
+	Ref        *VItem        // what RefName refers to, nil if unresolved
+
+	// rules checked against the value itself; see validator_rules.go
+	If, Then, Else, Not *VItem
+	AllOf, OneOf        []*VItem
+	DependentRequired   []VDependency
+	Compare             []VCompare
+
+	// rules of the keys of an object; see validator_object.go
+	NoAdditional  bool
+	Additional    *VItem
+	PatternItems  []VPatternItem
+	PropertyNames *VItem
+	MinProperties int64
+	MaxProperties int64
+
+	// rules of the elements of an array; see validator_array.go
+	PrefixItems []*VItem
+	UniqueItems bool
+	UniqueBy    string
+	Contains    *VItem
+	MinContains int64
+	MaxContains int64
 }
 
 type Validator struct {
-	Syntax    *DJSON
-	RootItems []*VItem
+	Syntax      *DJSON
+	RootItems   []*VItem
+	Definitions map[string]*VItem // "definitions" of the syntax
+
+	registry *SchemaRegistry
 }
 
 func NewValidator() *Validator {
@@ -193,7 +231,21 @@ func NewValidator() *Validator {
 	}
 }
 
+// Compile returns false if the syntax has any problem CompileE reports. What
+// is valid of it is compiled all the same, and a "regexp" which does not
+// compile matches nothing.
+
 func (m *Validator) Compile(syntax string) bool {
+	if err := m.CompileE(syntax); err != nil {
+		m.compile(syntax)
+		return false
+	}
+
+	return true
+}
+
+func (m *Validator) compile(syntax string) bool {
+	m.Syntax = NewDJSON()
 	m.Syntax.Parse(syntax)
 
 	if !m.Syntax.IsObject() && !m.Syntax.IsString() && !m.Syntax.IsArray() {
@@ -219,6 +271,9 @@ func (m *Validator) Compile(syntax string) bool {
 		}
 	}
 
+	m.compileDefinitions()
+	m.resolveRefs()
+
 	return true
 }
 
@@ -282,6 +337,8 @@ func GetVItem(name string, ejson *DJSON) *VItem {
 			eitem.CheckFunc = CheckFuncHex
 		case "BOOL":
 			eitem.Type = V_TYPE_BOOL
+		case "NULL":
+			eitem.Type = V_TYPE_NIL
 		}
 
 	} else if ejson.IsArray() {
@@ -295,13 +352,37 @@ func GetVItem(name string, ejson *DJSON) *VItem {
 			}
 		}
 
+		// absent, it passes none of the alternatives if all are required
+		eitem.IsRequred = len(eitem.SubItems) > 0
+		for _, vi := range eitem.SubItems {
+			eitem.IsRequred = eitem.IsRequred && vi.IsRequred
+		}
+
 	} else if ejson.IsObject() {
 
 		etype = ejson.GetAsString("type")
 		eitem.IsRequred = ejson.GetAsBool("required")
+		if dv, ok := ejson.Get("default"); ok {
+			eitem.Default = dv.Clone()
+		}
 		if ejson.GetAsString("regexp") != "" {
-			eitem.RegExp, _ = regexp.Compile(ejson.GetAsString("regexp"))
+			if re, err := regexp.Compile(ejson.GetAsString("regexp")); err == nil {
+				eitem.RegExp = re
+			} else {
+				eitem.RegExp = NoneRegExp
+			}
 		}
+		if ev, ok := ejson.Get("enum"); ok && ev.IsArray() {
+			eitem.Enum = make([]interface{}, 0, ev.Length())
+			for idx := 0; idx < ev.Length(); idx++ {
+				v, _ := ev.Get(idx)
+				eitem.Enum = append(eitem.Enum, v.GetAsInterface())
+			}
+		} else if cv, ok := ejson.Get("const"); ok {
+			eitem.Enum = []interface{}{cv.GetAsInterface()}
+		}
+		eitem.IgnoreCase = ejson.GetAsBool("ignoreCase")
+		eitem.parseRules(ejson)
 
 		switch etype {
 		case "INT":
@@ -339,6 +420,9 @@ func GetVItem(name string, ejson *DJSON) *VItem {
 			eitem.Max = ejson.GetAsInt("max", 8192)
 			eitem.CheckFunc = CheckFuncMinMaxString
 		case "OBJECT":
+			if eitem.parseObjectRules(ejson) {
+				eitem.Type = V_TYPE_OBJECT
+			}
 			subJson, ok := ejson.GetAsObject("object")
 			if ok {
 				eitem.Type = V_TYPE_OBJECT
@@ -401,6 +485,8 @@ func GetVItem(name string, ejson *DJSON) *VItem {
 			eitem.CheckFunc = CheckFuncHex
 		case "BOOL":
 			eitem.Type = V_TYPE_BOOL
+		case "NULL":
+			eitem.Type = V_TYPE_NIL
 		}
 
 		if etype == "BIN" || etype == "DEC" || etype == "HEX" {
@@ -417,6 +503,7 @@ func GetVItem(name string, ejson *DJSON) *VItem {
 		if etype == "ARRAY" || etype == "NONEMPTY.ARRAY" {
 			eitem.Type = V_TYPE_ARRAY
 			eitem.Max = ejson.GetAsInt("max", int64(9007199254740991))
+			eitem.parseArrayRules(ejson)
 			oa, ok := ejson.Get("array") // type of element
 			if ok {
 				eitem.SubItems = make([]*VItem, 0)
@@ -437,92 +524,23 @@ func GetVItem(name string, ejson *DJSON) *VItem {
 
 	}
 
-	switch etype {
-	case "TIMESTAMP":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 0
-		eitem.Max = 10
-		eitem.CheckFunc = CheckFuncTimestamp
-	case "YYYYMMDD":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 8
-		eitem.Max = 10
-		eitem.CheckFunc = CheckFuncYYYYMMDD
-	case "YYMMDD":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 6
-		eitem.Max = 8
-		eitem.CheckFunc = CheckFuncYYMMDD
-	case "HHMMSS":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 6
-		eitem.Max = 8
-		eitem.CheckFunc = CheckFuncHHMMSS
-	case "HHMM":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 4
-		eitem.Max = 5
-		eitem.CheckFunc = CheckFuncHHMM
-	case "EMAIL":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 3
-		eitem.Max = 255
-		eitem.CheckFunc = CheckFuncEmail
-	case "INT.STRING", "INT_STRING":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 1
-		eitem.Max = 17
-		eitem.CheckFunc = CheckFuncIntString
-	case "FLOAT.STRING", "FLOAT_STRING":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 1
-		eitem.Max = 24
-		eitem.CheckFunc = CheckFuncFloatString
-	case "BOOL.STRING", "BOOL_STRING":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 4
-		eitem.Max = 5
-		eitem.CheckFunc = CheckFuncBoolString
-	case "UUID":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 36
-		eitem.Max = 36
-		eitem.CheckFunc = CheckFuncUUID
-	case "ISO31661A2":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 2
-		eitem.Max = 2
-		eitem.CheckFunc = CheckISO31661A2
-	case "ISO31662":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 5
-		eitem.Max = 5
-		eitem.CheckFunc = CheckISO31662
-	case "BASE64":
+	if f, ok := lookupFormat(etype); ok {
 		eitem.Type = V_TYPE_STRING
-		eitem.Min = 0
-		eitem.Max = 8192
-		eitem.CheckFunc = CheckBase64
-	case "TELEPHONE":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 4
-		eitem.Max = 20
-		eitem.CheckFunc = CheckTelephone
-	case "HEX64.IF.EXIST":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 0
-		eitem.Max = 16
-		eitem.CheckFunc = CheckHexIfExist
-	case "HEX128.IF.EXIST":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 0
-		eitem.Max = 32
-		eitem.CheckFunc = CheckHexIfExist
-	case "HEX256.IF.EXIST":
-		eitem.Type = V_TYPE_STRING
-		eitem.Min = 0
-		eitem.Max = 64
-		eitem.CheckFunc = CheckHexIfExist
+		eitem.Min = f.min
+		eitem.Max = f.max
+		eitem.CheckFunc = f.check
+	}
+
+	if ejson.IsObject() && ejson.GetAsString("ref") != "" { // ref replaces type
+		eitem.Type = V_TYPE_REF
+		eitem.RefName = ejson.GetAsString("ref")
+	}
+
+	eitem.TypeName = etype
+	if eitem.Type == V_TYPE_MULTI {
+		eitem.TypeName = "MULTI"
+	} else if eitem.Type == V_TYPE_REF {
+		eitem.TypeName = eitem.RefName
 	}
 
 	return eitem
@@ -561,202 +579,7 @@ func (m *Validator) IsValid(tjson *DJSON) bool {
 }
 
 func CheckVItem(vi *VItem, tjson *DJSON) bool {
-	if vi.Name == "" {
-		return false
-	}
-
-	var vtype string
-
-	if vi.Name == "__root__" || vi.Name == "__array__" {
-		vtype = tjson.GetType()
-	} else {
-		vtype = tjson.GetType(vi.Name)
-	}
-
-	//log.Println("CheckVItem ", vi.Name, " ", vtype, " ", vi.Type, " ", tjson.ToString())
-
-	if vtype == "" && !vi.IsRequred {
-		return true
-	}
-
-	switch vi.Type {
-	case V_TYPE_INT:
-		if vtype != "int" {
-			return false
-		}
-
-		var si int64
-
-		if vi.Name == "__root__" || vi.Name == "__array__" {
-			si = tjson.GetAsInt()
-		} else {
-			si = tjson.GetAsInt(vi.Name)
-		}
-
-		if vi.Max < si || vi.Min > si {
-			return false
-		}
-
-	case V_TYPE_NUMBER:
-		if vtype != "float" && vtype != "int" {
-			return false
-		}
-
-		fallthrough
-
-	case V_TYPE_FLOAT:
-		if vtype != "float" {
-			return false
-		}
-
-		var sf float64
-
-		if vi.Name == "__root__" || vi.Name == "__array__" {
-			sf = tjson.GetAsFloat()
-		} else {
-			sf = tjson.GetAsFloat(vi.Name)
-		}
-
-		if vi.MaxFloat < sf || vi.MinFloat > sf {
-			return false
-		}
-	case V_TYPE_STRING:
-		if vtype != "string" {
-			return false
-		}
-
-		var ss string
-
-		if vi.Name == "__root__" || vi.Name == "__array__" {
-			ss = tjson.GetAsString()
-		} else {
-			ss = tjson.GetAsString(vi.Name)
-		}
-
-		lenv := int64(len(ss))
-
-		if lenv > vi.Max || lenv < vi.Min {
-			return false
-		}
-
-		if vi.RegExp != nil {
-			return vi.RegExp.Match([]byte(ss))
-		}
-
-		if vi.CheckFunc != nil {
-			return vi.CheckFunc(ss, vi.Min, vi.Max)
-		}
-
-	case V_TYPE_OBJECT:
-		if vi.Name == "__root__" && vtype != "object" {
-			return false
-		}
-
-		var so *DJSON
-		var ok bool
-
-		if vi.Name == "__root__" || vi.Name == "__array__" {
-			so, ok = tjson.GetAsObject()
-			if !ok {
-				return false
-			}
-		} else {
-			so, ok = tjson.GetAsObject(vi.Name)
-		}
-
-		if vi.IsRequred && !ok {
-			return false
-		}
-
-		if !ok {
-			return true
-		}
-
-		for _, svi := range vi.SubItems {
-			if !CheckVItem(svi, so) {
-				return false
-			}
-		}
-
-	case V_TYPE_ARRAY:
-		if vi.Name == "__root__" && vtype != "array" {
-			return false
-		}
-
-		var sa *DJSON
-		var ok bool
-
-		if vi.Name == "__root__" || vi.Name == "__array__" {
-			sa, ok = tjson.GetAsArray()
-			if !ok {
-				return false
-			}
-		} else {
-			sa, ok = tjson.GetAsArray(vi.Name)
-		}
-
-		if vi.IsRequred && !ok {
-			return false
-		}
-
-		if !ok {
-			return true
-		}
-
-		lenv := int64(sa.Length())
-		if lenv > vi.Max || lenv < vi.Min {
-			return false
-		}
-
-		if ok {
-			if len(vi.SubItems) == 0 {
-				return true
-			}
-
-			sa.Seek() // valid element type
-			for ssa := sa.Next(); ssa != nil; ssa = sa.Next() {
-				isValid := false
-				for _, svi := range vi.SubItems {
-					if CheckVItem(svi, ssa) {
-						isValid = true
-						break
-					}
-				}
-				if !isValid {
-					return false
-				}
-			}
-		}
-
-	case V_TYPE_BOOL:
-
-		var ok bool
-		if vi.Name == "__root__" || vi.Name == "__array__" {
-			ok = tjson.IsBool()
-		} else {
-			ok = tjson.IsBool(vi.Name)
-		}
-
-		if vi.IsRequred && !ok {
-			return false
-		}
-
-	case V_TYPE_MULTI:
-
-		isValid := false
-
-		for _, svi := range vi.SubItems {
-			if CheckVItem(svi, tjson) {
-				isValid = true
-				break
-			}
-		}
-
-		if !isValid {
-			return false
-		}
-
-	}
-
-	return true
+	c := &vcheck{}
+	c.item(vi, tjson, nil)
+	return len(c.violations) == 0
 }
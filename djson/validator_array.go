@@ -0,0 +1,131 @@
+package djson
+
+import (
+	"strconv"
+)
+
+// Rules of the elements of an ARRAY:
+//
+//	"prefixItems": [...]        the element at each index matches the schema;
+//	                            "array" checks the rest
+//	"uniqueItems": true         no two elements are Equal
+//	"uniqueBy": "id"            no two elements have the same value at the key
+//	                            or path; elements without it are skipped
+//	"contains": {...}           some elements match the schema, at least
+//	                            "minContains" (1) and at most "maxContains"
+//
+// A duplicate is VIOLATION_UNIQUE at the later element (at its value for
+// uniqueBy) with the index of the first as Expected. Too few or too many
+// elements matching contains is VIOLATION_CONTAINS with the bound as
+// Expected, e.g. ">=1".
+
+func (m *VItem) parseArrayRules(ejson *DJSON) {
+	if pa, ok := ejson.GetAsArray("prefixItems"); ok {
+		for idx := 0; idx < pa.Length(); idx++ {
+			ps, _ := pa.Get(idx)
+			m.PrefixItems = append(m.PrefixItems, GetVItem("__array__", ps))
+		}
+	}
+
+	m.UniqueItems = ejson.GetAsBool("uniqueItems")
+	m.UniqueBy = ejson.GetAsString("uniqueBy")
+
+	if cs, ok := ejson.Get("contains"); ok {
+		m.Contains = GetVItem("__array__", cs)
+		m.MinContains = ejson.GetAsInt("minContains", 1)
+		m.MaxContains = ejson.GetAsInt("maxContains", maxSafeInt)
+	}
+}
+
+// elementItems returns the schemas of the rules.
+
+func (m *VItem) elementItems() []*VItem {
+	ret := append([]*VItem{}, m.PrefixItems...)
+	if m.Contains != nil {
+		ret = append(ret, m.Contains)
+	}
+	return ret
+}
+
+// itemsAt returns the schemas the element at idx matches one of.
+
+func (m *VItem) itemsAt(idx int) []*VItem {
+	if idx < len(m.PrefixItems) {
+		return m.PrefixItems[idx : idx+1]
+	}
+	return m.SubItems
+}
+
+func (m *vcheck) arrayRules(vi *VItem, sa *DJSON, path []interface{}) {
+	if vi.UniqueItems {
+		m.unique(sa, path, nil)
+	}
+
+	if vi.UniqueBy != "" && !m.done() {
+		m.unique(sa, path, keyOrPathTokens(vi.UniqueBy))
+	}
+
+	if vi.Contains == nil || m.done() {
+		return
+	}
+
+	contains := int64(0)
+	for idx := 0; idx < sa.Length(); idx++ {
+		if es, _ := sa.Get(idx); passes(vi.Contains, es) {
+			contains++
+		}
+	}
+
+	if contains < vi.MinContains {
+		m.add(path, VIOLATION_CONTAINS, ">="+strconv.FormatInt(vi.MinContains, 10), sa.GetAsInterface())
+	} else if contains > vi.MaxContains {
+		m.add(path, VIOLATION_CONTAINS, "<="+strconv.FormatInt(vi.MaxContains, 10), sa.GetAsInterface())
+	}
+}
+
+// unique reports the elements equal to an earlier one, or whose values at
+// the tokens are.
+
+func (m *vcheck) unique(sa *DJSON, path []interface{}, tokens []interface{}) {
+	type seen struct {
+		value *DJSON
+		idx   int
+	}
+
+	s := newValueSet(nil)
+	buckets := make(map[string][]seen)
+
+	for idx := 0; idx < sa.Length(); idx++ {
+		es, _ := sa.Get(idx)
+		epath := appendPath(path, idx)
+
+		if tokens != nil {
+			var ok bool
+			if es, ok = es.getByTokens(tokens...); !ok {
+				continue
+			}
+			for _, token := range tokens {
+				epath = appendPath(epath, token)
+			}
+		}
+
+		key, id := s.identity(es)
+
+		first := -1
+		for _, e := range buckets[key] {
+			if e.value.Equal(id) {
+				first = e.idx
+				break
+			}
+		}
+
+		if first < 0 {
+			buckets[key] = append(buckets[key], seen{id, idx})
+			continue
+		}
+
+		if m.add(epath, VIOLATION_UNIQUE, strconv.Itoa(first), es.GetAsInterface()); m.done() {
+			return
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package djson
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	SCHEMA_INVALID_JSON    = "invalid json"
+	SCHEMA_UNKNOWN_TYPE    = "unknown type"
+	SCHEMA_UNKNOWN_KEYWORD = "unknown keyword"
+	SCHEMA_KEYWORD_TYPE    = "wrong keyword type"
+	SCHEMA_MIN_MAX         = "min > max"
+	SCHEMA_INVALID_REGEXP  = "invalid regexp"
+	SCHEMA_UNKNOWN_REF     = "unknown ref"
+)
+
+// SchemaProblem is a problem of a Validator syntax at Path, a path in the
+// syntax document.
+
+type SchemaProblem struct {
+	Path    string
+	Code    string // SCHEMA_*
+	Message string
+}
+
+// SchemaError is returned by CompileE with every problem of the syntax.
+
+type SchemaError struct {
+	Problems []SchemaProblem
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for idx, p := range e.Problems {
+		msgs[idx] = fmt.Sprintf("%s at %s", p.Message, p.Path)
+	}
+	return "invalid schema: " + strings.Join(msgs, "; ")
+}
+
+// keywords every object form takes, including the rules
+var schemaCommonKeywords = []string{"type", "required", "regexp", "default", "if", "then", "else", "not", "allOf", "oneOf"}
+
+// keywords the scalar types take, i.e. other than OBJECT and ARRAY
+var schemaScalarKeywords = []string{"enum", "const", "ignoreCase"}
+
+// schemaTypeKeywords are the types with the other keywords they take. The
+// formats, see validator_format.go, take none.
+var schemaTypeKeywords = map[string][]string{
+	"INT":             {"min", "max"},
+	"UNIXTIME":        {"min", "max"},
+	"UINT":            {"min", "max"},
+	"FLOAT":           {"min", "max"},
+	"NUMBER":          {"min", "max"},
+	"STRING":          {"min", "max", "size"},
+	"NONEMPTY.STRING": {"min", "max", "size"},
+	"MIN.MAX.STRING":  {"min", "max"},
+	"BIN":             {"min", "max", "size"},
+	"DEC":             {"min", "max", "size"},
+	"HEX":             {"min", "max", "size"},
+	"OBJECT":          {"object", "dependentRequired", "compare", "additional", "patternProperties", "propertyNames", "minProperties", "maxProperties"},
+	"ARRAY":           {"min", "max", "size", "array", "prefixItems", "uniqueItems", "uniqueBy", "contains", "minContains", "maxContains"},
+	"NONEMPTY.ARRAY":  {"min", "max", "size", "array", "prefixItems", "uniqueItems", "uniqueBy", "contains", "minContains", "maxContains"},
+	"BOOL":            {},
+	"NULL":            {},
+}
+
+// CompileE compiles syntax like Compile but fails with a *SchemaError
+// listing every problem: invalid JSON, unknown type or keyword, a keyword of
+// the wrong type (including an enum value not of the type), min > max,
+// invalid regexp and a "#name" ref to no definition. Compile returns false
+// for such a syntax.
+
+func (m *Validator) CompileE(syntax string) error {
+	sjson, err := NewDJSON().ParseWithOptions(syntax, ParseOptions{})
+	if err != nil {
+		return &SchemaError{Problems: []SchemaProblem{{Code: SCHEMA_INVALID_JSON, Message: err.Error()}}}
+	}
+
+	c := &schemaCheck{defs: make(map[string]bool)}
+	for _, k := range sjson.GetKeys("definitions") {
+		c.defs[k] = true
+	}
+
+	if sjson.IsObject() || sjson.IsArray() || sjson.IsString() {
+		c.node(sjson, nil)
+	} else {
+		c.add(nil, SCHEMA_KEYWORD_TYPE, "syntax must be an object, an array or a string")
+	}
+
+	if len(c.problems) > 0 {
+		return &SchemaError{Problems: c.problems}
+	}
+
+	v := &Validator{Syntax: NewDJSON(), registry: m.registry}
+	v.compile(syntax)
+
+	for _, ref := range v.resolveRefs() {
+		if strings.HasPrefix(ref, "#") {
+			c.add(nil, SCHEMA_UNKNOWN_REF, "ref %q refers to itself without a type", ref)
+		}
+	}
+
+	if len(c.problems) > 0 {
+		return &SchemaError{Problems: c.problems}
+	}
+
+	m.Syntax, m.RootItems, m.Definitions = v.Syntax, v.RootItems, v.Definitions
+
+	return nil
+}
+
+func isSchemaType(name string) bool {
+	if _, ok := schemaTypeKeywords[name]; ok {
+		return true
+	}
+	_, ok := lookupFormat(name)
+	return ok
+}
+
+type schemaCheck struct {
+	problems []SchemaProblem
+	defs     map[string]bool // names of the definitions
+}
+
+func (m *schemaCheck) add(path []interface{}, code, format string, a ...interface{}) {
+	m.problems = append(m.problems, SchemaProblem{
+		Path:    BuildPath(path...),
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// node checks a type: a type name, an array of alternatives or an object.
+
+func (m *schemaCheck) node(s *DJSON, path []interface{}) {
+	switch {
+	case s.IsString():
+		if name := s.String; name == "MIN.MAX.STRING" || !isSchemaType(name) {
+			m.add(path, SCHEMA_UNKNOWN_TYPE, "unknown type %q", name)
+		}
+	case s.IsArray():
+		for idx := 0; idx < s.Length(); idx++ {
+			es, _ := s.Get(idx)
+			m.node(es, appendPath(path, idx))
+		}
+	case s.IsObject():
+		m.object(s, path)
+	default:
+		m.add(path, SCHEMA_KEYWORD_TYPE, "type must be a string, an array or an object")
+	}
+}
+
+func (m *schemaCheck) object(s *DJSON, path []interface{}) {
+	if s.HasKey("ref") {
+		m.ref(s, path)
+		return
+	}
+
+	// rules alone need no type
+	tv, ok := s.Get("type")
+	if ok && !tv.IsString() || !ok && !s.HasKeys("if") && !s.HasKeys("not") && !s.HasKeys("allOf") && !s.HasKeys("oneOf") {
+		m.add(appendPath(path, "type"), SCHEMA_KEYWORD_TYPE, "type must be a string")
+	}
+
+	etype := s.GetAsString("type")
+	known := isSchemaType(etype)
+	if ok && tv.IsString() && !known {
+		m.add(appendPath(path, "type"), SCHEMA_UNKNOWN_TYPE, "unknown type %q", etype)
+	}
+
+	allowed := make(map[string]bool)
+	for _, k := range schemaCommonKeywords {
+		allowed[k] = true
+	}
+	for name, ks := range schemaTypeKeywords {
+		if name == etype || !known { // any keyword of an unknown type
+			for _, k := range ks {
+				allowed[k] = true
+			}
+		}
+	}
+
+	if path == nil {
+		allowed["definitions"] = true
+	}
+
+	scalar := etype != "OBJECT" && etype != "ARRAY" && etype != "NONEMPTY.ARRAY"
+	if scalar {
+		for _, k := range schemaScalarKeywords {
+			allowed[k] = true
+		}
+	}
+
+	isFloat := etype == "FLOAT" || etype == "NUMBER"
+
+	for _, k := range s.GetKeys() {
+		kpath := appendPath(path, k)
+		kv, _ := s.Get(k)
+
+		if !allowed[k] {
+			if known {
+				m.add(kpath, SCHEMA_UNKNOWN_KEYWORD, "keyword %q is not used by %s", k, etype)
+			} else {
+				m.add(kpath, SCHEMA_UNKNOWN_KEYWORD, "unknown keyword %q", k)
+			}
+			continue
+		}
+
+		switch k {
+		case "required":
+			if !kv.IsBool() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "required must be a bool")
+			}
+		case "regexp":
+			if !kv.IsString() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "regexp must be a string")
+			} else if _, err := regexp.Compile(kv.String); err != nil {
+				m.add(kpath, SCHEMA_INVALID_REGEXP, "%s", err.Error())
+			}
+		case "min", "max", "size":
+			if isFloat && !kv.IsInt() && !kv.IsFloat() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "%s must be a number", k)
+			} else if !isFloat && !kv.IsInt() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "%s must be an integer", k)
+			}
+		case "ignoreCase":
+			if !kv.IsBool() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "ignoreCase must be a bool")
+			}
+		case "const":
+			m.enumValue(kv, etype, kpath)
+		case "enum":
+			if !kv.IsArray() || kv.Length() == 0 {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "enum must be a non-empty array")
+				continue
+			}
+			for idx := 0; idx < kv.Length(); idx++ {
+				ev, _ := kv.Get(idx)
+				m.enumValue(ev, etype, appendPath(kpath, idx))
+			}
+		case "object":
+			if !kv.IsObject() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "object must be an object")
+				continue
+			}
+			for _, name := range kv.GetKeys() {
+				sub, _ := kv.Get(name)
+				m.node(sub, appendPath(kpath, name))
+			}
+		case "array":
+			m.node(kv, kpath)
+		case "definitions":
+			m.definitions(kv, kpath)
+		case "if", "then", "else", "not":
+			m.node(kv, kpath)
+		case "allOf", "oneOf":
+			if !kv.IsArray() || kv.Length() == 0 {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "%s must be a non-empty array", k)
+				continue
+			}
+			m.node(kv, kpath)
+		case "dependentRequired":
+			m.dependentRequired(kv, kpath)
+		case "additional":
+			if !kv.IsBool() {
+				m.node(kv, kpath)
+			}
+		case "propertyNames":
+			m.node(kv, kpath)
+		case "patternProperties":
+			if !kv.IsObject() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "patternProperties must be an object")
+				continue
+			}
+			for _, pattern := range kv.GetKeys() {
+				ppath := appendPath(kpath, pattern)
+				if _, err := regexp.Compile(pattern); err != nil {
+					m.add(ppath, SCHEMA_INVALID_REGEXP, "%s", err.Error())
+				}
+				ps, _ := kv.Get(pattern)
+				m.node(ps, ppath)
+			}
+		case "prefixItems":
+			if !kv.IsArray() || kv.Length() == 0 {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "prefixItems must be a non-empty array")
+				continue
+			}
+			m.node(kv, kpath)
+		case "contains":
+			m.node(kv, kpath)
+		case "uniqueItems":
+			if !kv.IsBool() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "uniqueItems must be a bool")
+			}
+		case "uniqueBy":
+			if !kv.IsString() || kv.String == "" {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "uniqueBy must be a key or a path")
+			}
+		case "minProperties", "maxProperties", "minContains", "maxContains":
+			if !kv.IsInt() || kv.Int < 0 {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "%s must be a non-negative integer", k)
+			}
+		case "compare":
+			m.compare(kv, kpath)
+		}
+	}
+
+	for _, bounds := range [][2]string{{"minProperties", "maxProperties"}, {"minContains", "maxContains"}} {
+		if s.IsInt(bounds[0]) && s.IsInt(bounds[1]) && s.GetAsInt(bounds[0]) > s.GetAsInt(bounds[1]) {
+			m.add(path, SCHEMA_MIN_MAX, "%s %d is greater than %s %d", bounds[0], s.GetAsInt(bounds[0]), bounds[1], s.GetAsInt(bounds[1]))
+		}
+	}
+
+	if (s.IsInt("min") || s.IsFloat("min")) && (s.IsInt("max") || s.IsFloat("max")) &&
+		s.GetAsFloat("min") > s.GetAsFloat("max") {
+		m.add(path, SCHEMA_MIN_MAX, "min %s is greater than max %s", s.GetAsString("min"), s.GetAsString("max"))
+	}
+}
+
+// enumValue checks an allowed value has the type of etype.
+
+func (m *schemaCheck) enumValue(v *DJSON, etype string, path []interface{}) {
+	var ok bool
+	var expected string
+
+	switch etype {
+	case "INT", "UINT", "UNIXTIME":
+		ok, expected = v.IsInt(), "an integer"
+	case "FLOAT", "NUMBER":
+		ok, expected = v.IsNumeric(), "a number"
+	case "BOOL":
+		ok, expected = v.IsBool(), "a bool"
+	case "":
+		ok, expected = v.IsInt() || v.IsFloat() || v.IsString() || v.IsBool(), "a scalar"
+	default:
+		ok, expected = v.IsString(), "a string"
+	}
+
+	if !ok {
+		m.add(path, SCHEMA_KEYWORD_TYPE, "value must be %s", expected)
+	}
+}
+
+// ref checks an object form with "ref", which takes no type. Only local refs
+// are checked as the registry is not known yet.
+
+func (m *schemaCheck) ref(s *DJSON, path []interface{}) {
+	for _, k := range s.GetKeys() {
+		kpath := appendPath(path, k)
+		kv, _ := s.Get(k)
+
+		switch k {
+		case "ref":
+			if !kv.IsString() || kv.String == "" {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "ref must be a string")
+			} else if strings.HasPrefix(kv.String, "#") && !m.defs[kv.String[1:]] {
+				m.add(kpath, SCHEMA_UNKNOWN_REF, "no definition %q", kv.String[1:])
+			}
+		case "required":
+			if !kv.IsBool() {
+				m.add(kpath, SCHEMA_KEYWORD_TYPE, "required must be a bool")
+			}
+		case "default":
+		case "definitions":
+			if path == nil {
+				m.definitions(kv, kpath)
+				continue
+			}
+			fallthrough
+		default:
+			m.add(kpath, SCHEMA_UNKNOWN_KEYWORD, "keyword %q is not used with ref", k)
+		}
+	}
+}
+
+func (m *schemaCheck) definitions(s *DJSON, path []interface{}) {
+	if !s.IsObject() {
+		m.add(path, SCHEMA_KEYWORD_TYPE, "definitions must be an object")
+		return
+	}
+
+	for _, k := range s.GetKeys() {
+		ds, _ := s.Get(k)
+		m.node(ds, appendPath(path, k))
+	}
+}
+
+func (m *schemaCheck) dependentRequired(s *DJSON, path []interface{}) {
+	if !s.IsObject() {
+		m.add(path, SCHEMA_KEYWORD_TYPE, "dependentRequired must be an object")
+		return
+	}
+
+	for _, k := range s.GetKeys() {
+		ds, _ := s.Get(k)
+		ok := ds.IsArray()
+		for idx := 0; ok && idx < ds.Length(); idx++ {
+			ok = ds.IsString(idx)
+		}
+		if !ok {
+			m.add(appendPath(path, k), SCHEMA_KEYWORD_TYPE, "must be an array of keys")
+		}
+	}
+}
+
+func (m *schemaCheck) compare(s *DJSON, path []interface{}) {
+	if !s.IsArray() {
+		m.add(path, SCHEMA_KEYWORD_TYPE, "compare must be an array")
+		return
+	}
+
+	for idx := 0; idx < s.Length(); idx++ {
+		cpath := appendPath(path, idx)
+		cs, ok := s.GetAsObject(idx)
+		if !ok {
+			m.add(cpath, SCHEMA_KEYWORD_TYPE, "compare must be an object")
+			continue
+		}
+
+		for _, k := range cs.GetKeys() {
+			switch k {
+			case "field", "other":
+				if cs.GetAsString(k) == "" {
+					m.add(appendPath(cpath, k), SCHEMA_KEYWORD_TYPE, "%s must be a key or a path", k)
+				}
+			case "op":
+				if !compareOps[cs.GetAsString(k)] {
+					m.add(appendPath(cpath, k), SCHEMA_KEYWORD_TYPE, "op must be one of ==, !=, <, <=, > and >=")
+				}
+			default:
+				m.add(appendPath(cpath, k), SCHEMA_UNKNOWN_KEYWORD, "unknown keyword %q", k)
+			}
+		}
+
+		for _, k := range []string{"field", "op", "other"} {
+			if !cs.HasKey(k) {
+				m.add(appendPath(cpath, k), SCHEMA_KEYWORD_TYPE, "%s is missing", k)
+			}
+		}
+	}
+}
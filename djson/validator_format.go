@@ -0,0 +1,134 @@
+package djson
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// A format is a type of strings which pass a check, with a fixed range of
+// length; the syntax takes no "min", "max" or "size" for it. The formats
+// here are built in, and RegisterFormat adds more under a namespace, e.g.
+// "acme:KR_BRN", so they never collide with the built-in types or with
+// formats of other packages. A format is looked up at Compile, so it must
+// be registered before the syntax using it is compiled.
+
+type vformat struct {
+	check      func(string, ...int64) bool
+	min        int64
+	max        int64
+	jsonSchema string // "format" of JSON Schema, if any
+}
+
+var formats = map[string]vformat{
+	"TIMESTAMP":       {check: CheckFuncTimestamp, min: 0, max: 10},
+	"YYYYMMDD":        {check: CheckFuncYYYYMMDD, min: 8, max: 10},
+	"YYMMDD":          {check: CheckFuncYYMMDD, min: 6, max: 8},
+	"HHMMSS":          {check: CheckFuncHHMMSS, min: 6, max: 8},
+	"HHMM":            {check: CheckFuncHHMM, min: 4, max: 5},
+	"EMAIL":           {check: CheckFuncEmail, min: 3, max: 255, jsonSchema: "email"},
+	"INT.STRING":      {check: CheckFuncIntString, min: 1, max: 17},
+	"INT_STRING":      {check: CheckFuncIntString, min: 1, max: 17},
+	"FLOAT.STRING":    {check: CheckFuncFloatString, min: 1, max: 24},
+	"FLOAT_STRING":    {check: CheckFuncFloatString, min: 1, max: 24},
+	"BOOL.STRING":     {check: CheckFuncBoolString, min: 4, max: 5},
+	"BOOL_STRING":     {check: CheckFuncBoolString, min: 4, max: 5},
+	"UUID":            {check: CheckFuncUUID, min: 36, max: 36, jsonSchema: "uuid"},
+	"ISO31661A2":      {check: CheckISO31661A2, min: 2, max: 2},
+	"ISO31662":        {check: CheckISO31662, min: 5, max: 5},
+	"BASE64":          {check: CheckBase64, min: 0, max: 8192},
+	"TELEPHONE":       {check: CheckTelephone, min: 4, max: 20},
+	"HEX64.IF.EXIST":  {check: CheckHexIfExist, min: 0, max: 16},
+	"HEX128.IF.EXIST": {check: CheckHexIfExist, min: 0, max: 32},
+	"HEX256.IF.EXIST": {check: CheckHexIfExist, min: 0, max: 64},
+	"DATETIME":        {check: CheckFuncDateTime, min: 20, max: 35, jsonSchema: "date-time"},
+	"IPV4":            {check: CheckFuncIPv4, min: 7, max: 15, jsonSchema: "ipv4"},
+	"IPV6":            {check: CheckFuncIPv6, min: 2, max: 45, jsonSchema: "ipv6"},
+	"URI":             {check: CheckFuncURI, min: 3, max: 8192, jsonSchema: "uri"},
+	"DURATION":        {check: CheckFuncDuration, min: 3, max: 64, jsonSchema: "duration"},
+}
+
+var formatsLock sync.RWMutex
+
+var FormatNameRegExp = regexp.MustCompile(`^[a-z][a-z0-9_-]*:[A-Za-z0-9_.-]+$`)
+var DurationRegExp = regexp.MustCompile(`^P([0-9]+W|([0-9]+Y)?([0-9]+M)?([0-9]+D)?(T([0-9]+H)?([0-9]+M)?([0-9]+(\.[0-9]+)?S)?)?)$`)
+
+// RegisterFormat adds a format named "namespace:NAME" whose values are
+// strings of up to 8192 bytes passing check. It fails if the name has no
+// namespace or is already registered.
+
+func RegisterFormat(name string, check func(string, ...int64) bool) error {
+	if !FormatNameRegExp.MatchString(name) || check == nil {
+		return invalidFormatNameError
+	}
+
+	formatsLock.Lock()
+	defer formatsLock.Unlock()
+
+	if _, ok := formats[name]; ok {
+		return formatExistsError
+	}
+
+	formats[name] = vformat{check: check, min: 0, max: 8192, jsonSchema: name}
+
+	return nil
+}
+
+func lookupFormat(name string) (vformat, bool) {
+	formatsLock.RLock()
+	defer formatsLock.RUnlock()
+
+	f, ok := formats[name]
+	return f, ok
+}
+
+// formatOfJSONSchema returns the type of a "format" of JSON Schema.
+
+func formatOfJSONSchema(jsonSchema string) (string, bool) {
+	formatsLock.RLock()
+	defer formatsLock.RUnlock()
+
+	for name, f := range formats {
+		if f.jsonSchema != "" && f.jsonSchema == jsonSchema {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// RFC 3339, e.g. 2024-01-02T15:04:05+09:00
+
+func CheckFuncDateTime(ts string, vi ...int64) bool {
+	_, err := time.Parse(time.RFC3339, ts)
+	return err == nil
+}
+
+func CheckFuncIPv4(ts string, vi ...int64) bool {
+	return strings.IndexByte(ts, ':') < 0 && net.ParseIP(ts) != nil
+}
+
+func CheckFuncIPv6(ts string, vi ...int64) bool {
+	return strings.IndexByte(ts, ':') >= 0 && net.ParseIP(ts) != nil
+}
+
+// absolute URI, with a scheme
+
+func CheckFuncURI(ts string, vi ...int64) bool {
+	if strings.IndexFunc(ts, unicode.IsSpace) >= 0 {
+		return false
+	}
+
+	u, err := url.Parse(ts)
+	return err == nil && u.Scheme != ""
+}
+
+// ISO 8601 duration, e.g. P1Y2M3DT4H5M6S or P2W
+
+func CheckFuncDuration(ts string, vi ...int64) bool {
+	return DurationRegExp.MatchString(ts) && ts != "P" && !strings.HasSuffix(ts, "T")
+}
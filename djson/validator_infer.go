@@ -0,0 +1,235 @@
+package djson
+
+// inferFormats are the string types InferSchema tries, most specific first.
+var inferFormats = []string{
+	"UUID", "EMAIL", "DATETIME", "IPV4", "IPV6", "YYYYMMDD", "TIMESTAMP", "HHMMSS", "HHMM", "YYMMDD",
+	"ISO31662", "ISO31661A2", "BOOL.STRING", "INT.STRING", "FLOAT.STRING", "TELEPHONE", "HEX",
+}
+
+var inferFormatItems = func() map[string]*VItem {
+	items := make(map[string]*VItem)
+	for _, format := range inferFormats {
+		items[format] = GetVItem("__root__", NewDJSON().Put(format))
+	}
+	return items
+}()
+
+type schemaStat struct {
+	count int // number of times the value was present
+
+	nulls int
+	bools int
+
+	ints     int
+	floats   int
+	minInt   int64
+	maxInt   int64
+	minFloat float64
+	maxFloat float64
+
+	strs    int
+	minLen  int
+	maxLen  int
+	formats []string // formats every string so far matched
+
+	objects int
+	keys    []string
+	fields  map[string]*schemaStat
+
+	arrays  int
+	minSize int
+	maxSize int
+	elem    *schemaStat
+}
+
+// InferSchema returns a Validator syntax which every sample satisfies. Types
+// are INT, FLOAT or NUMBER (both seen), STRING or a format detected by the
+// CheckFunc* detectors, BOOL, OBJECT and ARRAY with min/max taken from the
+// samples. A key is required when it is present in every sample object. A
+// value seen with several types becomes an array of alternatives, NULL among
+// them for a value which is null in any sample.
+
+func InferSchema(samples ...*DJSON) *DJSON {
+	root := new(schemaStat)
+	for _, sample := range samples {
+		if sample != nil {
+			root.observe(sample.GetAsInterface())
+		}
+	}
+
+	if root.count == 0 {
+		return NewDJSON()
+	}
+
+	return NewDJSON().Put(root.schema(false))
+}
+
+func (m *schemaStat) observe(v interface{}) {
+	m.count++
+
+	switch t := v.(type) {
+	case nil:
+		m.nulls++
+	case bool:
+		m.bools++
+	case int64:
+		if m.ints == 0 || t < m.minInt {
+			m.minInt = t
+		}
+		if m.ints == 0 || t > m.maxInt {
+			m.maxInt = t
+		}
+		m.ints++
+		m.observeNumber(float64(t))
+	case float64:
+		m.floats++
+		m.observeNumber(t)
+	case string:
+		m.observeString(t)
+	case *DO:
+		if m.fields == nil {
+			m.fields = make(map[string]*schemaStat)
+		}
+		m.objects++
+
+		for _, k := range t.Keys() {
+			field, ok := m.fields[k]
+			if !ok {
+				field = new(schemaStat)
+				m.fields[k] = field
+				m.keys = append(m.keys, k)
+			}
+
+			ev, _ := t.Get(k)
+			field.observe(ev)
+		}
+	case *DA:
+		if m.elem == nil {
+			m.elem = new(schemaStat)
+		}
+
+		size := t.Size()
+		if m.arrays == 0 || size < m.minSize {
+			m.minSize = size
+		}
+		if m.arrays == 0 || size > m.maxSize {
+			m.maxSize = size
+		}
+		m.arrays++
+
+		for idx := 0; idx < size; idx++ {
+			m.elem.observe(t.Element[idx])
+		}
+	}
+}
+
+func (m *schemaStat) observeNumber(f float64) {
+	if m.ints+m.floats == 1 || f < m.minFloat {
+		m.minFloat = f
+	}
+	if m.ints+m.floats == 1 || f > m.maxFloat {
+		m.maxFloat = f
+	}
+}
+
+func (m *schemaStat) observeString(s string) {
+	if m.strs == 0 {
+		m.minLen = len(s)
+		m.maxLen = len(s)
+		m.formats = inferFormats
+	}
+	m.strs++
+
+	if len(s) < m.minLen {
+		m.minLen = len(s)
+	}
+	if len(s) > m.maxLen {
+		m.maxLen = len(s)
+	}
+
+	if len(s) == 0 {
+		m.formats = nil
+		return
+	}
+
+	sjson := NewDJSON().Put(s)
+	formats := make([]string, 0, len(m.formats))
+	for _, format := range m.formats {
+		if CheckVItem(inferFormatItems[format], sjson) {
+			formats = append(formats, format)
+		}
+	}
+	m.formats = formats
+}
+
+// schema returns the syntax of the value, in the object form so that
+// "required" can be set.
+
+func (m *schemaStat) schema(required bool) interface{} {
+	alts := make([]*DO, 0)
+
+	if m.nulls > 0 {
+		alts = append(alts, NewObject().Put("type", "NULL"))
+	}
+
+	switch {
+	case m.ints > 0 && m.floats > 0:
+		alts = append(alts, NewObject().Put("type", "NUMBER").Put("min", m.minFloat).Put("max", m.maxFloat))
+	case m.ints > 0:
+		alts = append(alts, NewObject().Put("type", "INT").Put("min", m.minInt).Put("max", m.maxInt))
+	case m.floats > 0:
+		alts = append(alts, NewObject().Put("type", "FLOAT").Put("min", m.minFloat).Put("max", m.maxFloat))
+	}
+
+	if m.strs > 0 {
+		alt := NewObject()
+		if len(m.formats) > 0 {
+			alt.Put("type", m.formats[0])
+		} else {
+			alt.Put("type", "STRING")
+		}
+
+		if len(m.formats) == 0 || m.formats[0] == "HEX" {
+			alt.Put("min", int64(m.minLen)).Put("max", int64(m.maxLen))
+		}
+		alts = append(alts, alt)
+	}
+
+	if m.bools > 0 {
+		alts = append(alts, NewObject().Put("type", "BOOL"))
+	}
+
+	if m.objects > 0 {
+		obj := NewObject()
+		for _, k := range m.keys {
+			field := m.fields[k]
+			obj.Put(k, field.schema(field.count == m.objects))
+		}
+		alts = append(alts, NewObject().Put("type", "OBJECT").Put("object", obj))
+	}
+
+	if m.arrays > 0 {
+		alt := NewObject().Put("type", "ARRAY").Put("min", int64(m.minSize)).Put("max", int64(m.maxSize))
+		if m.elem.count > 0 {
+			alt.Put("array", m.elem.schema(false))
+		}
+		alts = append(alts, alt)
+	}
+
+	for _, alt := range alts {
+		if required {
+			alt.Put("required", true)
+		}
+	}
+
+	if len(alts) == 1 {
+		return alts[0]
+	}
+
+	ret := NewArray()
+	for _, alt := range alts {
+		ret.PushBack(alt)
+	}
+
+	return ret
+}
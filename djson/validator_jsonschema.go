@@ -0,0 +1,888 @@
+package djson
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const SCHEMA_UNSUPPORTED = "unsupported"
+
+const JSON_SCHEMA_DRAFT = "https://json-schema.org/draft/2020-12/schema"
+
+const maxSafeInt = int64(9007199254740991)
+
+// ImportJSONSchema converts a JSON Schema (draft 2020-12) to Validator
+// syntax. It covers type, properties, required, additionalProperties,
+// patternProperties, propertyNames, minProperties/maxProperties, items,
+// prefixItems, uniqueItems, contains, minContains/maxContains,
+// minLength/maxLength, minItems/maxItems, minimum/maximum, pattern, enum,
+// const, format (email, uuid, date-time, ipv4, ipv6, uri, duration and the
+// registered formats by their names; others are annotations),
+// allOf/anyOf/oneOf/not, if/then/else, dependentRequired and local $ref. A
+// $ref to $defs or definitions becomes a ref to a definition, and any other
+// is replaced by its target. Without type, the type is taken from the
+// keywords, e.g. properties means object. The type null is NULL, so
+// ["string", "null"] accepts null, and minLength/maxLength with a format are
+// checked in allOf. Problems, such as a false schema, fail with a
+// *SchemaError whose paths are in the schema.
+
+func ImportJSONSchema(schema string) (*DJSON, error) {
+	sjson, err := NewDJSON().ParseWithOptions(schema, ParseOptions{})
+	if err != nil {
+		return nil, &SchemaError{Problems: []SchemaProblem{{Code: SCHEMA_INVALID_JSON, Message: err.Error()}}}
+	}
+
+	c := &schemaCheck{}
+	im := &jsonSchemaImport{check: c, root: sjson, refs: make(map[string]bool), defs: NewObject()}
+	syntax := im.node(sjson, nil)
+
+	if len(c.problems) > 0 {
+		return nil, &SchemaError{Problems: c.problems}
+	}
+
+	if im.defs.Size() > 0 {
+		do, ok := syntax.(*DO)
+		if !ok { // alternatives cannot have definitions
+			im.defs.Put("__root__", syntax)
+			do = NewObject().Put("ref", "#__root__")
+		}
+		syntax = do.Put("definitions", im.defs)
+	}
+
+	return NewDJSON().Put(syntax), nil
+}
+
+// CompileJSONSchema compiles a JSON Schema as ImportJSONSchema converts it.
+
+func (m *Validator) CompileJSONSchema(schema string) error {
+	syntax, err := ImportJSONSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	m.compile(syntax.ToStringWith(SerializeOptions{}))
+
+	return nil
+}
+
+type jsonSchemaImport struct {
+	check *schemaCheck
+	root  *DJSON
+	refs  map[string]bool // $refs being resolved
+	defs  *DO             // definitions of $defs which are referred
+}
+
+// node returns the syntax of s: a type name, an array of alternatives or
+// an object.
+
+func (m *jsonSchemaImport) node(s *DJSON, path []interface{}) interface{} {
+	if s.IsBool() {
+		if !s.Bool {
+			m.check.add(path, SCHEMA_UNSUPPORTED, "false schema")
+		}
+		return NewObject()
+	}
+
+	if !s.IsObject() {
+		m.check.add(path, SCHEMA_KEYWORD_TYPE, "schema must be an object or a bool")
+		return NewObject()
+	}
+
+	return m.withRules(m.base(s, path), s, path)
+}
+
+func (m *jsonSchemaImport) base(s *DJSON, path []interface{}) interface{} {
+	if s.HasKey("$ref") {
+		return m.ref(s, path)
+	}
+
+	if s.HasKey("const") || s.HasKey("enum") {
+		return m.enum(s, path)
+	}
+
+	var types []string
+	switch tv, _ := s.Get("type"); {
+	case tv == nil:
+		types = []string{inferJSONSchemaType(s)}
+	case tv.IsString():
+		types = []string{tv.String}
+	case tv.IsArray():
+		for idx := 0; idx < tv.Length(); idx++ {
+			types = append(types, tv.GetAsString(idx))
+		}
+	default:
+		m.check.add(appendPath(path, "type"), SCHEMA_KEYWORD_TYPE, "type must be a string or an array")
+		return NewObject()
+	}
+
+	nodes := make([]interface{}, 0, len(types))
+	for _, t := range types {
+		nodes = append(nodes, m.typed(s, t, path))
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	return NewArray().Put(nodes)
+}
+
+// withRules adds the rules of s to base. anyOf becomes alternatives in
+// allOf, and base goes to allOf too unless it can take the rules itself.
+
+func (m *jsonSchemaImport) withRules(base interface{}, s *DJSON, path []interface{}) interface{} {
+	rules := NewObject()
+	allOf := NewArray()
+
+	if alts, ok := s.Get("anyOf"); ok {
+		allOf.PushBack(m.alternatives(alts, appendPath(path, "anyOf")))
+	}
+
+	for _, k := range []string{"allOf", "oneOf"} {
+		if alts, ok := s.Get(k); ok {
+			if !alts.IsArray() || alts.Length() == 0 {
+				m.check.add(appendPath(path, k), SCHEMA_KEYWORD_TYPE, "must be a non-empty array")
+				continue
+			}
+
+			nodes := NewArray()
+			for idx := 0; idx < alts.Length(); idx++ {
+				as, _ := alts.Get(idx)
+				nodes.PushBack(m.node(as, appendPath(appendPath(path, k), idx)))
+			}
+
+			if k == "allOf" {
+				allOf.Put(nodes)
+			} else {
+				rules.Put(k, nodes)
+			}
+		}
+	}
+
+	for _, k := range []string{"not", "if", "then", "else"} {
+		if rs, ok := s.Get(k); ok {
+			rules.Put(k, m.node(rs, appendPath(path, k)))
+		}
+	}
+
+	if deps, ok := s.Get("dependentRequired"); ok {
+		rules.Put("dependentRequired", cloneValue(deps.GetAsInterface()))
+	}
+
+	if allOf.Size() == 0 && rules.Size() == 0 {
+		return base
+	}
+
+	if do, ok := base.(*DO); ok && !do.HasKey("ref") {
+		if prev, ok := do.GetAsArray("allOf"); ok {
+			allOf.Element = append(prev.Element, allOf.Element...)
+		}
+		if allOf.Size() > 0 {
+			do.Put("allOf", allOf)
+		}
+		for _, k := range rules.Keys() {
+			v, _ := rules.Get(k)
+			do.Put(k, v)
+		}
+		return do
+	}
+
+	allOf.Insert(0, base)
+	return rules.Put("allOf", allOf)
+}
+
+func inferJSONSchemaType(s *DJSON) string {
+	switch {
+	case s.HasKeys("properties") || s.HasKeys("required") || s.HasKeys("dependentRequired") ||
+		s.HasKeys("additionalProperties") || s.HasKeys("patternProperties") || s.HasKeys("propertyNames") ||
+		s.HasKeys("minProperties") || s.HasKeys("maxProperties"):
+		return "object"
+	case s.HasKeys("items") || s.HasKeys("minItems") || s.HasKeys("maxItems") || s.HasKeys("prefixItems") ||
+		s.HasKeys("uniqueItems") || s.HasKeys("contains"):
+		return "array"
+	case s.HasKeys("minLength") || s.HasKeys("maxLength") || s.HasKeys("pattern") || s.HasKeys("format"):
+		return "string"
+	case s.HasKeys("minimum") || s.HasKeys("maximum"):
+		return "number"
+	}
+	return ""
+}
+
+func (m *jsonSchemaImport) typed(s *DJSON, t string, path []interface{}) interface{} {
+	ret := NewObject()
+
+	switch t {
+	case "":
+		return ret
+	case "null":
+		ret.Put("type", "NULL")
+	case "boolean":
+		ret.Put("type", "BOOL")
+	case "integer":
+		ret.Put("type", "INT")
+		if s.HasKey("minimum") {
+			ret.Put("min", int64(math.Ceil(s.GetAsFloat("minimum"))))
+		}
+		if s.HasKey("maximum") {
+			ret.Put("max", int64(math.Floor(s.GetAsFloat("maximum"))))
+		}
+	case "number":
+		ret.Put("type", "NUMBER")
+		if s.HasKey("minimum") {
+			ret.Put("min", s.GetAsFloat("minimum"))
+		}
+		if s.HasKey("maximum") {
+			ret.Put("max", s.GetAsFloat("maximum"))
+		}
+	case "string":
+		if name, ok := formatOfJSONSchema(s.GetAsString("format")); ok {
+			ret.Put("type", name)
+			// a format has its own length, so the bounds are checked apart
+			if s.HasKey("minLength") || s.HasKey("maxLength") {
+				ret.Put("allOf", NewArray().PushBack(NewObject().
+					Put("type", "STRING").
+					Put("min", s.GetAsInt("minLength", 0)).
+					Put("max", s.GetAsInt("maxLength", maxSafeInt))))
+			}
+		} else {
+			ret.Put("type", "STRING")
+			ret.Put("min", s.GetAsInt("minLength", 0))
+			ret.Put("max", s.GetAsInt("maxLength", maxSafeInt))
+		}
+		if s.HasKey("pattern") {
+			pattern := s.GetAsString("pattern")
+			if _, err := regexp.Compile(pattern); err != nil {
+				m.check.add(appendPath(path, "pattern"), SCHEMA_INVALID_REGEXP, "%s", err.Error())
+			}
+			ret.Put("regexp", pattern)
+		}
+	case "array":
+		ret.Put("type", "ARRAY")
+		ret.Put("min", s.GetAsInt("minItems", 0))
+		ret.Put("max", s.GetAsInt("maxItems", maxSafeInt))
+		m.arrayRules(s, ret, path)
+	case "object":
+		ret.Put("type", "OBJECT")
+		ret.Put("object", m.properties(s, path))
+		m.objectRules(s, ret, path)
+	default:
+		m.check.add(appendPath(path, "type"), SCHEMA_UNKNOWN_TYPE, "unknown type %q", t)
+	}
+
+	if dv, ok := s.Get("default"); ok {
+		ret.Put("default", cloneValue(dv.GetAsInterface()))
+	}
+
+	return ret
+}
+
+// arrayRules puts items, which is the rest after prefixItems; false allows
+// no more elements.
+
+func (m *jsonSchemaImport) arrayRules(s *DJSON, ret *DO, path []interface{}) {
+	prefix := 0
+	if ps, ok := s.Get("prefixItems"); ok {
+		if !ps.IsArray() || ps.Length() == 0 {
+			m.check.add(appendPath(path, "prefixItems"), SCHEMA_KEYWORD_TYPE, "prefixItems must be a non-empty array")
+		} else {
+			prefix = ps.Length()
+			tuple := NewArray()
+			for idx := 0; idx < prefix; idx++ {
+				es, _ := ps.Get(idx)
+				tuple.PushBack(m.node(es, appendPath(appendPath(path, "prefixItems"), idx)))
+			}
+			ret.Put("prefixItems", tuple)
+		}
+	}
+
+	if items, ok := s.Get("items"); ok {
+		switch {
+		case items.IsArray():
+			m.check.add(appendPath(path, "items"), SCHEMA_UNSUPPORTED, "items must be a schema, use prefixItems for tuples")
+		case items.IsBool() && !items.Bool && prefix > 0:
+			if max := s.GetAsInt("maxItems", maxSafeInt); int64(prefix) < max {
+				ret.Put("max", int64(prefix))
+			}
+		default:
+			ret.Put("array", m.node(items, appendPath(path, "items")))
+		}
+	}
+
+	if s.IsBool("uniqueItems") && s.GetAsBool("uniqueItems") {
+		ret.Put("uniqueItems", true)
+	}
+
+	if cs, ok := s.Get("contains"); ok {
+		ret.Put("contains", m.node(cs, appendPath(path, "contains")))
+		for _, k := range []string{"minContains", "maxContains"} {
+			if s.IsInt(k) {
+				ret.Put(k, s.GetAsInt(k))
+			}
+		}
+	}
+}
+
+func (m *jsonSchemaImport) objectRules(s *DJSON, ret *DO, path []interface{}) {
+	if av, ok := s.Get("additionalProperties"); ok {
+		if !av.IsBool() {
+			ret.Put("additional", m.node(av, appendPath(path, "additionalProperties")))
+		} else if !av.Bool {
+			ret.Put("additional", false)
+		}
+	}
+
+	if pps, ok := s.Get("patternProperties"); ok && pps.IsObject() {
+		patterns := NewObject()
+		for _, k := range pps.GetKeys() {
+			ps, _ := pps.Get(k)
+			ppath := appendPath(appendPath(path, "patternProperties"), k)
+			if _, err := regexp.Compile(k); err != nil {
+				m.check.add(ppath, SCHEMA_INVALID_REGEXP, "%s", err.Error())
+			}
+			patterns.Put(k, m.node(ps, ppath))
+		}
+		ret.Put("patternProperties", patterns)
+	}
+
+	if ns, ok := s.Get("propertyNames"); ok {
+		ret.Put("propertyNames", m.node(ns, appendPath(path, "propertyNames")))
+	}
+
+	for _, k := range []string{"minProperties", "maxProperties"} {
+		if s.IsInt(k) {
+			ret.Put(k, s.GetAsInt(k))
+		}
+	}
+}
+
+func (m *jsonSchemaImport) properties(s *DJSON, path []interface{}) *DO {
+	ret := NewObject()
+
+	if props, ok := s.Get("properties"); ok {
+		if !props.IsObject() {
+			m.check.add(appendPath(path, "properties"), SCHEMA_KEYWORD_TYPE, "properties must be an object")
+		} else {
+			for _, k := range props.GetKeys() {
+				ps, _ := props.Get(k)
+				ret.Put(k, m.node(ps, appendPath(appendPath(path, "properties"), k)))
+			}
+		}
+	}
+
+	required, ok := s.Get("required")
+	if !ok {
+		return ret
+	}
+	if !required.IsArray() {
+		m.check.add(appendPath(path, "required"), SCHEMA_KEYWORD_TYPE, "required must be an array")
+		return ret
+	}
+
+	for idx := 0; idx < required.Length(); idx++ {
+		k := required.GetAsString(idx)
+
+		v, ok := ret.Get(k)
+		if !ok {
+			v = NewObject()
+		}
+
+		ret.Put(k, requiredNode(v))
+	}
+
+	return ret
+}
+
+// requiredNode marks a syntax node required; alternatives are all marked as
+// the key is missing for each of them. A required key of any type must have
+// a non-null value.
+
+func requiredNode(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return NewObject().Put("type", t).Put("required", true)
+	case *DO:
+		if t.Size() == 0 { // any value, which only the typed alternatives can require
+			return requiredNode(NewArray().Put([]interface{}{
+				"NUMBER",
+				NewObject().Put("type", "STRING").Put("max", maxSafeInt), // as enum, unbounded
+				"BOOL",
+				NewObject().Put("type", "OBJECT").Put("object", NewObject()),
+				"ARRAY",
+				"NULL",
+			}))
+		}
+		return t.Put("required", true)
+	case *DA:
+		for idx := range t.Element {
+			t.Element[idx] = requiredNode(t.Element[idx])
+		}
+	}
+	return v
+}
+
+func (m *jsonSchemaImport) alternatives(alts *DJSON, path []interface{}) interface{} {
+	if !alts.IsArray() || alts.Length() == 0 {
+		m.check.add(path, SCHEMA_KEYWORD_TYPE, "must be a non-empty array")
+		return NewObject()
+	}
+
+	ret := NewArray()
+	for idx := 0; idx < alts.Length(); idx++ {
+		as, _ := alts.Get(idx)
+		switch t := m.node(as, appendPath(path, idx)).(type) {
+		case *DA:
+			ret.Element = append(ret.Element, t.Element...)
+		default:
+			ret.PushBack(t)
+		}
+	}
+
+	return ret
+}
+
+// enum becomes an alternative with the enum keyword for each type of the
+// values.
+
+func (m *jsonSchemaImport) enum(s *DJSON, path []interface{}) interface{} {
+	values := NewDJSON(JSON_ARRAY)
+	vpath := appendPath(path, "enum")
+
+	if cv, ok := s.Get("const"); ok {
+		values.Array.PushBack(cv.GetAsInterface())
+		vpath = appendPath(path, "const")
+	} else if ev, _ := s.Get("enum"); ev.IsArray() {
+		values = ev
+	} else {
+		m.check.add(vpath, SCHEMA_KEYWORD_TYPE, "enum must be an array")
+		return NewObject()
+	}
+
+	ret := NewArray()
+	byType := make(map[string]*DA)
+
+	for idx := 0; idx < values.Length(); idx++ {
+		v, _ := values.Get(idx)
+
+		var vtype string
+		switch v.JsonType {
+		case JSON_STRING:
+			vtype = "STRING"
+		case JSON_INT, JSON_FLOAT:
+			vtype = "NUMBER"
+		case JSON_BOOL:
+			vtype = "BOOL"
+		case JSON_NULL:
+			vtype = "NULL"
+		default:
+			m.check.add(appendPath(vpath, idx), SCHEMA_UNSUPPORTED, "enum of %s", v.GetType())
+			continue
+		}
+
+		if byType[vtype] == nil {
+			byType[vtype] = NewArray()
+			alt := NewObject().Put("type", vtype)
+			if vtype == "STRING" {
+				alt.Put("max", maxSafeInt)
+			}
+			if vtype != "NULL" { // null is the only value
+				alt.Put("enum", byType[vtype])
+			}
+			ret.PushBack(alt)
+		}
+		byType[vtype].PushBack(v.GetAsInterface())
+	}
+
+	if ret.Size() == 1 {
+		return ret.Element[0]
+	}
+
+	return ret
+}
+
+func (m *jsonSchemaImport) ref(s *DJSON, path []interface{}) interface{} {
+	ref := s.GetAsString("$ref")
+	rpath := appendPath(path, "$ref")
+
+	if !strings.HasPrefix(ref, "#") {
+		m.check.add(rpath, SCHEMA_UNSUPPORTED, "external $ref %q", ref)
+		return NewObject()
+	}
+
+	if name, ok := jsonSchemaDefName(ref); ok {
+		if !m.defs.HasKey(name) {
+			m.defs.Put(name, NewObject()) // for a recursive ref
+			if target, ok := resolveJSONPointer(m.root, ref[1:]); ok {
+				m.defs.Put(name, m.node(target, []interface{}{"$defs", name}))
+			} else {
+				m.check.add(rpath, SCHEMA_UNSUPPORTED, "$ref %q not found", ref)
+			}
+		}
+		return NewObject().Put("ref", "#"+name)
+	}
+
+	if m.refs[ref] {
+		m.check.add(rpath, SCHEMA_UNSUPPORTED, "recursive $ref %q", ref)
+		return NewObject()
+	}
+
+	target, ok := m.root, true
+	if pointer := strings.TrimPrefix(ref, "#"); pointer != "" {
+		target, ok = resolveJSONPointer(m.root, pointer)
+	}
+	if !ok {
+		m.check.add(rpath, SCHEMA_UNSUPPORTED, "$ref %q not found", ref)
+		return NewObject()
+	}
+
+	m.refs[ref] = true
+	defer delete(m.refs, ref)
+
+	return m.node(target, rpath)
+}
+
+// jsonSchemaDefName returns the name of "#/$defs/name" or
+// "#/definitions/name".
+
+func jsonSchemaDefName(ref string) (string, bool) {
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if name := strings.TrimPrefix(ref, prefix); name != ref && name != "" && !strings.Contains(name, "/") {
+			return strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~"), true
+		}
+	}
+	return "", false
+}
+
+func resolveJSONPointer(root *DJSON, pointer string) (*DJSON, bool) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	cur := root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		var next *DJSON
+		var ok bool
+		if cur.IsArray() {
+			idx, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, false
+			}
+			next, ok = cur.Get(idx)
+		} else if cur.IsObject() {
+			next, ok = cur.Get(token)
+		}
+
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+
+	return cur, true
+}
+
+// ToJSONSchema exports the compiled syntax as JSON Schema (draft 2020-12).
+// Formats are exported as patterns and as format where JSON Schema has one,
+// registered formats by their names; ISO31661A2 and ISO31662 only by their
+// shape, and an enum with ignoreCase, uniqueBy and compare are left out.
+
+func (m *Validator) ToJSONSchema() *DJSON {
+	var ret *DO
+
+	switch {
+	case len(m.RootItems) == 0:
+		ret = NewObject()
+	case m.Syntax.IsObject():
+		ret = jsonSchemaOf(m.RootItems[0])
+	default:
+		ret = jsonSchemaAlternatives(m.RootItems)
+	}
+
+	schema := NewObject().Put("$schema", JSON_SCHEMA_DRAFT)
+	for _, k := range ret.Keys() {
+		v, _ := ret.Get(k)
+		schema.Put(k, v)
+	}
+
+	if len(m.Definitions) > 0 {
+		defs := NewObject()
+		for _, k := range m.definitionNames() {
+			defs.Put(k, jsonSchemaOf(m.Definitions[k]))
+		}
+		schema.Put("$defs", defs)
+	}
+
+	return NewDJSON().Put(schema)
+}
+
+// patterns of the formats which are not a regexp in GetVItem
+var jsonSchemaPatterns = map[string]string{
+	"INT.STRING":   `^[+-]?[0-9]+$`,
+	"FLOAT.STRING": `^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`,
+	"BOOL.STRING":  `^([Tt][Rr][Uu][Ee]|[Ff][Aa][Ll][Ss][Ee])$`,
+	"ISO31661A2":   `^[A-Za-z]{2}$`,
+	"ISO31662":     `^[A-Za-z]{2}-`,
+	"BASE64":       `^([A-Za-z0-9+/]{4})*([A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=)?$`,
+}
+
+func jsonSchemaPattern(vi *VItem) string {
+	if vi.RegExp != nil {
+		return vi.RegExp.String()
+	}
+
+	switch vi.TypeName {
+	case "TIMESTAMP":
+		return TimestampRegExp.String()
+	case "YYYYMMDD":
+		return YYYYMMDDRegExp.String()
+	case "YYMMDD":
+		return YYMMDDRegExp.String()
+	case "HHMMSS":
+		return HHMMSSRegExp.String()
+	case "HHMM":
+		return HHMMRegExp.String()
+	case "EMAIL":
+		return EmailRegExp.String()
+	case "UUID":
+		return UUIDRegExp.String()
+	case "TELEPHONE":
+		return TelRegExp.String()
+	case "BIN":
+		return BinRegExp.String()
+	case "DEC":
+		return DecRegExp.String()
+	case "HEX", "HEX64.IF.EXIST", "HEX128.IF.EXIST", "HEX256.IF.EXIST":
+		return HexRegExp.String()
+	}
+
+	return jsonSchemaPatterns[vi.TypeName]
+}
+
+func jsonSchemaAlternatives(items []*VItem) *DO {
+	if len(items) == 1 {
+		return jsonSchemaOf(items[0])
+	}
+
+	alts := NewArray()
+	for _, vi := range items {
+		alts.PushBack(jsonSchemaOf(vi))
+	}
+
+	return NewObject().Put("anyOf", alts)
+}
+
+func jsonSchemaOf(vi *VItem) *DO {
+	ret := NewObject()
+
+	switch vi.Type {
+	case V_TYPE_INT:
+		ret.Put("type", "integer")
+		if vi.Min > -maxSafeInt {
+			ret.Put("minimum", vi.Min)
+		}
+		if vi.Max < maxSafeInt {
+			ret.Put("maximum", vi.Max)
+		}
+
+	case V_TYPE_FLOAT, V_TYPE_NUMBER:
+		ret.Put("type", "number")
+		if vi.MinFloat > -math.MaxFloat64 {
+			ret.Put("minimum", vi.MinFloat)
+		}
+		if vi.MaxFloat < math.MaxFloat64 {
+			ret.Put("maximum", vi.MaxFloat)
+		}
+
+	case V_TYPE_STRING:
+		pattern := jsonSchemaPattern(vi)
+
+		// the length is either Min or Max
+		if vi.RegExp == nil && (vi.TypeName == "MIN.MAX.STRING" || strings.HasSuffix(vi.TypeName, ".IF.EXIST")) {
+			alts := NewArray()
+			for _, l := range []int64{vi.Min, vi.Max} {
+				alt := NewObject().Put("type", "string").Put("minLength", l).Put("maxLength", l)
+				if pattern != "" {
+					alt.Put("pattern", pattern)
+				}
+				alts.PushBack(alt)
+			}
+			ret.Put("anyOf", alts)
+			break
+		}
+
+		ret.Put("type", "string")
+		if f, ok := lookupFormat(vi.TypeName); ok && f.jsonSchema != "" {
+			ret.Put("format", f.jsonSchema)
+		}
+		if vi.Min > 0 {
+			ret.Put("minLength", vi.Min)
+		}
+		if vi.Max < maxSafeInt {
+			ret.Put("maxLength", vi.Max)
+		}
+		if pattern != "" {
+			ret.Put("pattern", pattern)
+		}
+
+	case V_TYPE_BOOL:
+		ret.Put("type", "boolean")
+
+	case V_TYPE_NIL:
+		ret.Put("type", "null")
+
+	case V_TYPE_OBJECT:
+		ret.Put("type", "object")
+		props := NewObject()
+		required := NewArray()
+		for _, svi := range vi.SubItems {
+			props.Put(svi.Name, jsonSchemaOf(svi))
+			if isRequiredVItem(svi) {
+				required.PushBack(svi.Name)
+			}
+		}
+		ret.Put("properties", props)
+		if required.Size() > 0 {
+			ret.Put("required", required)
+		}
+
+		if vi.NoAdditional {
+			ret.Put("additionalProperties", false)
+		} else if vi.Additional != nil {
+			ret.Put("additionalProperties", jsonSchemaOf(vi.Additional))
+		}
+		if len(vi.PatternItems) > 0 {
+			patterns := NewObject()
+			for _, pi := range vi.PatternItems {
+				patterns.Put(pi.Pattern.String(), jsonSchemaOf(pi.Item))
+			}
+			ret.Put("patternProperties", patterns)
+		}
+		if vi.PropertyNames != nil {
+			ret.Put("propertyNames", jsonSchemaOf(vi.PropertyNames))
+		}
+		if vi.MinProperties > 0 {
+			ret.Put("minProperties", vi.MinProperties)
+		}
+		if vi.MaxProperties < maxSafeInt {
+			ret.Put("maxProperties", vi.MaxProperties)
+		}
+
+	case V_TYPE_ARRAY:
+		ret.Put("type", "array")
+		if vi.Min > 0 {
+			ret.Put("minItems", vi.Min)
+		}
+		if vi.Max < maxSafeInt {
+			ret.Put("maxItems", vi.Max)
+		}
+		if len(vi.PrefixItems) > 0 {
+			tuple := NewArray()
+			for _, pvi := range vi.PrefixItems {
+				tuple.PushBack(jsonSchemaOf(pvi))
+			}
+			ret.Put("prefixItems", tuple)
+		}
+		if len(vi.SubItems) > 0 {
+			ret.Put("items", jsonSchemaAlternatives(vi.SubItems))
+		}
+		if vi.UniqueItems {
+			ret.Put("uniqueItems", true)
+		}
+		if vi.Contains != nil {
+			ret.Put("contains", jsonSchemaOf(vi.Contains))
+			if vi.MinContains != 1 {
+				ret.Put("minContains", vi.MinContains)
+			}
+			if vi.MaxContains < maxSafeInt {
+				ret.Put("maxContains", vi.MaxContains)
+			}
+		}
+
+	case V_TYPE_MULTI:
+		return jsonSchemaAlternatives(vi.SubItems)
+
+	case V_TYPE_REF:
+		ret.Put("$ref", jsonSchemaRef(vi.RefName))
+	}
+
+	if len(vi.Enum) > 0 && !vi.IgnoreCase {
+		ret.Put("enum", NewArray().Put(vi.Enum))
+	}
+
+	jsonSchemaRules(vi, ret)
+
+	if vi.Default != nil {
+		ret.Put("default", cloneValue(vi.Default.GetAsInterface()))
+	}
+
+	return ret
+}
+
+// jsonSchemaRules puts the rules of vi but compare, which JSON Schema has
+// not.
+
+func jsonSchemaRules(vi *VItem, ret *DO) {
+	for _, rule := range []struct {
+		key   string
+		items []*VItem
+	}{{"allOf", vi.AllOf}, {"oneOf", vi.OneOf}} {
+		if len(rule.items) > 0 {
+			alts := NewArray()
+			for _, svi := range rule.items {
+				alts.PushBack(jsonSchemaOf(svi))
+			}
+			ret.Put(rule.key, alts)
+		}
+	}
+
+	for _, rule := range []struct {
+		key  string
+		item *VItem
+	}{{"not", vi.Not}, {"if", vi.If}, {"then", vi.Then}, {"else", vi.Else}} {
+		if rule.item != nil {
+			ret.Put(rule.key, jsonSchemaOf(rule.item))
+		}
+	}
+
+	if len(vi.DependentRequired) > 0 {
+		deps := NewObject()
+		for _, dep := range vi.DependentRequired {
+			keys := NewArray()
+			for _, k := range dep.Required {
+				keys.PushBack(k)
+			}
+			deps.Put(dep.Key, keys)
+		}
+		ret.Put("dependentRequired", deps)
+	}
+}
+
+// jsonSchemaRef turns "#name" into "#/$defs/name" and "v#name" into
+// "v#/$defs/name"; a registered Validator is referred by its name.
+
+func jsonSchemaRef(ref string) string {
+	idx := strings.Index(ref, "#")
+	if idx < 0 {
+		return ref
+	}
+
+	name := strings.ReplaceAll(strings.ReplaceAll(ref[idx+1:], "~", "~0"), "/", "~1")
+	return ref[:idx] + "#/$defs/" + name
+}
+
+func isRequiredVItem(vi *VItem) bool {
+	if vi.Type != V_TYPE_MULTI {
+		return vi.IsRequred && (vi.Type != V_TYPE_NULL || vi.hasRules())
+	}
+
+	for _, svi := range vi.SubItems {
+		if !isRequiredVItem(svi) {
+			return false
+		}
+	}
+
+	return len(vi.SubItems) > 0
+}
@@ -0,0 +1,239 @@
+package djson
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	NORMALIZE_DEFAULT = "default"
+	NORMALIZE_COERCE  = "coerce"
+	NORMALIZE_STRIP   = "strip"
+)
+
+// NormalizeOptions selects what Normalize fixes. FillDefaults puts the
+// "default" of an absent key, Coerce converts "42" to INT/FLOAT/NUMBER,
+// "true"/"false" to BOOL, numbers and bools to STRING and whole floats to
+// INT, and StripUnknown removes keys an OBJECT does not declare, by "object"
+// or patternProperties, unless it has an "additional" schema.
+
+type NormalizeOptions struct {
+	FillDefaults bool
+	Coerce       bool
+	StripUnknown bool
+}
+
+type NormalizeChange struct {
+	Path   string
+	Action string // NORMALIZE_*
+	From   interface{}
+	To     interface{}
+}
+
+// NormalizeReport lists the changes in document order. Valid is IsValid of
+// the normalized document.
+
+type NormalizeReport struct {
+	Changes []NormalizeChange
+	Valid   bool
+}
+
+type normalizer struct {
+	opts   NormalizeOptions
+	report *NormalizeReport
+}
+
+// Normalize returns a normalized copy of tjson and what was changed. Where
+// the syntax has alternatives the first one which the value satisfies as is
+// is used, otherwise the first one it satisfies after normalizing.
+
+func (m *Validator) Normalize(tjson *DJSON, opts NormalizeOptions) (*DJSON, *NormalizeReport) {
+	n := &normalizer{
+		opts:   opts,
+		report: &NormalizeReport{Changes: make([]NormalizeChange, 0)},
+	}
+
+	if tjson == nil {
+		n.report.Valid = m.IsValid(nil)
+		return nil, n.report
+	}
+
+	v := n.alternatives(m.RootItems, cloneValue(tjson.GetAsInterface()), nil)
+
+	ret := NewDJSON().Put(v)
+	n.report.Valid = m.IsValid(ret)
+
+	return ret, n.report
+}
+
+func (m *normalizer) record(path []interface{}, action string, from, to interface{}) {
+	m.report.Changes = append(m.report.Changes, NormalizeChange{
+		Path:   BuildPath(path...),
+		Action: action,
+		From:   from,
+		To:     to,
+	})
+}
+
+// satisfies checks v itself against vi, whatever key vi is for.
+
+func satisfies(vi *VItem, v interface{}) bool {
+	self := *vi
+	self.Name = "__array__"
+	return CheckVItem(&self, NewDJSON().Put(v))
+}
+
+func (m *normalizer) alternatives(items []*VItem, v interface{}, path []interface{}) interface{} {
+	if len(items) == 0 {
+		return v
+	}
+
+	for _, vi := range items {
+		if satisfies(vi, v) {
+			return m.value(vi, v, path)
+		}
+	}
+
+	// try each on a copy and keep the changes of the first which works
+	for _, vi := range items {
+		trial := &normalizer{opts: m.opts, report: &NormalizeReport{}}
+		if nv := trial.value(vi, cloneValue(v), path); satisfies(vi, nv) {
+			m.report.Changes = append(m.report.Changes, trial.report.Changes...)
+			return nv
+		}
+	}
+
+	return m.value(items[0], v, path)
+}
+
+func (m *normalizer) value(vi *VItem, v interface{}, path []interface{}) interface{} {
+	switch vi.Type {
+	case V_TYPE_MULTI:
+		return m.alternatives(vi.SubItems, v, path)
+	case V_TYPE_REF:
+		if vi.Ref != nil {
+			return m.value(vi.Ref, v, path)
+		}
+		return v
+	case V_TYPE_OBJECT:
+		if do, ok := v.(*DO); ok {
+			m.object(vi, do, path)
+		}
+		return v
+	case V_TYPE_ARRAY:
+		if da, ok := v.(*DA); ok {
+			for idx := 0; idx < da.Size(); idx++ {
+				ev := m.alternatives(vi.itemsAt(idx), da.Element[idx], appendPath(path, idx))
+				da.Element[idx] = ev
+			}
+		}
+		return v
+	}
+
+	if !m.opts.Coerce {
+		return v
+	}
+
+	if nv, ok := coerceValue(vi.Type, v); ok {
+		m.record(path, NORMALIZE_COERCE, v, nv)
+		return nv
+	}
+
+	return v
+}
+
+func (m *normalizer) object(vi *VItem, do *DO, path []interface{}) {
+	declared := make(map[string]bool)
+
+	for _, svi := range vi.SubItems {
+		declared[svi.Name] = true
+
+		ev, ok := do.Get(svi.Name)
+		if !ok {
+			if m.opts.FillDefaults && svi.Default != nil {
+				dv := cloneValue(svi.Default.GetAsInterface())
+				do.Put(svi.Name, dv)
+				m.record(appendPath(path, svi.Name), NORMALIZE_DEFAULT, nil, dv)
+			}
+			continue
+		}
+
+		do.Put(svi.Name, m.value(svi, ev, appendPath(path, svi.Name)))
+	}
+
+	if !m.opts.StripUnknown {
+		return
+	}
+
+	for _, k := range do.Keys() {
+		if !declared[k] && !vi.declares(k) && vi.Additional == nil {
+			ev, _ := do.Get(k)
+			do.Remove(k)
+			m.record(appendPath(path, k), NORMALIZE_STRIP, ev, nil)
+		}
+	}
+}
+
+func appendPath(path []interface{}, token interface{}) []interface{} {
+	ret := make([]interface{}, len(path), len(path)+1)
+	copy(ret, path)
+	return append(ret, token)
+}
+
+// coerceValue converts v to the type, returning false if v already has the
+// type or does not convert.
+
+func coerceValue(vtype int, v interface{}) (interface{}, bool) {
+	switch vtype {
+	case V_TYPE_INT:
+		switch t := v.(type) {
+		case string:
+			if i, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64); err == nil {
+				return i, true
+			}
+		case float64:
+			if i, err := floatToInt(t, "", ""); err == nil {
+				return i, true
+			}
+		}
+	case V_TYPE_FLOAT:
+		switch t := v.(type) {
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(t), 64); err == nil {
+				return f, true
+			}
+		case int64:
+			return float64(t), true
+		}
+	case V_TYPE_NUMBER:
+		if t, ok := v.(string); ok {
+			s := strings.TrimSpace(t)
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, true
+			}
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f, true
+			}
+		}
+	case V_TYPE_STRING:
+		switch t := v.(type) {
+		case int64:
+			return strconv.FormatInt(t, 10), true
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(t), true
+		}
+	case V_TYPE_BOOL:
+		if t, ok := v.(string); ok {
+			switch strings.ToLower(strings.TrimSpace(t)) {
+			case "true":
+				return true, true
+			case "false":
+				return false, true
+			}
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,129 @@
+package djson
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// Rules of the keys of an OBJECT, which needs no "object" with them:
+//
+//	"additional": false         no key but those of "object" and patternProperties
+//	"additional": {...}         the values of the other keys match the schema
+//	"patternProperties": {"^x-": {...}}  the values of the matching keys match
+//	"propertyNames": {...}      the keys match the schema, e.g. "UUID"
+//	"minProperties", "maxProperties"
+//
+// A key not allowed is VIOLATION_ADDITIONAL and a key not matching
+// propertyNames is VIOLATION_PROPERTY_NAME with the key as Actual. The
+// number of keys is VIOLATION_MIN or VIOLATION_MAX.
+
+type VPatternItem struct {
+	Pattern *regexp.Regexp
+	Item    *VItem
+}
+
+// parseObjectRules returns whether there is any.
+
+func (m *VItem) parseObjectRules(ejson *DJSON) bool {
+	m.MinProperties = ejson.GetAsInt("minProperties", 0)
+	m.MaxProperties = ejson.GetAsInt("maxProperties", maxSafeInt)
+
+	if av, ok := ejson.Get("additional"); ok {
+		if av.IsBool() {
+			m.NoAdditional = !av.Bool
+		} else {
+			m.Additional = GetVItem("__root__", av)
+		}
+	}
+
+	if pps, ok := ejson.GetAsObject("patternProperties"); ok {
+		for _, k := range pps.GetKeys() {
+			re, err := regexp.Compile(k)
+			if err != nil {
+				continue
+			}
+			ps, _ := pps.Get(k)
+			m.PatternItems = append(m.PatternItems, VPatternItem{Pattern: re, Item: GetVItem("__root__", ps)})
+		}
+	}
+
+	if ns, ok := ejson.Get("propertyNames"); ok {
+		m.PropertyNames = GetVItem("__root__", ns)
+	}
+
+	return m.NoAdditional || m.Additional != nil || len(m.PatternItems) > 0 || m.PropertyNames != nil ||
+		ejson.HasKeys("minProperties") || ejson.HasKeys("maxProperties")
+}
+
+// objectItems returns the schemas of the keys.
+
+func (m *VItem) objectItems() []*VItem {
+	ret := make([]*VItem, 0)
+	for _, pi := range m.PatternItems {
+		ret = append(ret, pi.Item)
+	}
+	for _, vi := range []*VItem{m.Additional, m.PropertyNames} {
+		if vi != nil {
+			ret = append(ret, vi)
+		}
+	}
+	return ret
+}
+
+// declares tells whether the key is in "object" or matches a pattern.
+
+func (m *VItem) declares(key string) bool {
+	for _, svi := range m.SubItems {
+		if svi.Name == key {
+			return true
+		}
+	}
+
+	for _, pi := range m.PatternItems {
+		if pi.Pattern.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *vcheck) objectRules(vi *VItem, so *DJSON, path []interface{}) {
+	keys := so.GetKeys()
+
+	if n := int64(len(keys)); n < vi.MinProperties {
+		m.add(path, VIOLATION_MIN, strconv.FormatInt(vi.MinProperties, 10), so.GetAsInterface())
+	} else if n > vi.MaxProperties {
+		m.add(path, VIOLATION_MAX, strconv.FormatInt(vi.MaxProperties, 10), so.GetAsInterface())
+	}
+
+	for _, k := range keys {
+		if m.done() {
+			return
+		}
+
+		kpath := appendPath(path, k)
+		value, _ := so.Get(k)
+
+		if vi.PropertyNames != nil && !passes(vi.PropertyNames, NewDJSON().Put(k)) {
+			m.add(kpath, VIOLATION_PROPERTY_NAME, vi.PropertyNames.TypeName, k)
+			continue
+		}
+
+		for _, pi := range vi.PatternItems {
+			if pi.Pattern.MatchString(k) {
+				m.item(pi.Item, value, kpath)
+			}
+		}
+
+		if vi.declares(k) {
+			continue
+		}
+
+		if vi.NoAdditional {
+			m.add(kpath, VIOLATION_ADDITIONAL, "", value.GetAsInterface())
+		} else if vi.Additional != nil {
+			m.item(vi.Additional, value, kpath)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package djson
+
+import (
+	"sort"
+	"strings"
+)
+
+// A "ref" names what to check a value with instead of a type: "#name" is a
+// definition in "definitions" at the root of the syntax, "name" a Validator
+// of the registry and "name#def" a definition of it. Refs are resolved at
+// Compile, and again when a Validator is registered, so definitions and
+// registered Validators may refer to each other and to themselves.
+
+// compileDefinitions compiles "definitions" of the syntax.
+
+func (m *Validator) compileDefinitions() {
+	m.Definitions = make(map[string]*VItem)
+
+	defs, ok := m.Syntax.GetAsObject("definitions")
+	if !ok || !m.Syntax.IsObject() {
+		return
+	}
+
+	for _, k := range defs.GetKeys() {
+		ds, _ := defs.Get(k)
+		m.Definitions[k] = GetVItem("__root__", ds)
+	}
+}
+
+// lookup returns the item ref refers to, nil if there is none.
+
+func (m *Validator) lookup(ref string) *VItem {
+	vname, dname := ref, ""
+	if idx := strings.Index(ref, "#"); idx >= 0 {
+		vname, dname = ref[:idx], ref[idx+1:]
+	}
+
+	v := m
+	if vname != "" {
+		if m.registry == nil {
+			return nil
+		}
+		if v = m.registry.validators[vname]; v == nil {
+			return nil
+		}
+		if dname == "" {
+			return v.rootItem()
+		}
+	}
+
+	return v.Definitions[dname]
+}
+
+// rootItem is the item IsValid checks a document with.
+
+func (m *Validator) rootItem() *VItem {
+	if len(m.RootItems) == 1 {
+		return m.RootItems[0]
+	}
+
+	return &VItem{Type: V_TYPE_MULTI, Name: "__root__", TypeName: "MULTI", SubItems: m.RootItems}
+}
+
+// resolveRefs resolves the refs which are not yet and returns those which
+// are still not.
+
+func (m *Validator) resolveRefs() []string {
+	unresolved := make([]string, 0)
+
+	var resolve func(items []*VItem)
+	resolve = func(items []*VItem) {
+		for _, vi := range items {
+			if vi.Type == V_TYPE_REF && vi.Ref == nil {
+				if vi.Ref = m.lookup(vi.RefName); vi.Ref == nil || refLoops(vi) {
+					vi.Ref = nil
+					unresolved = append(unresolved, vi.RefName)
+				}
+			}
+			resolve(vi.SubItems)
+			resolve(vi.applied())
+			resolve(vi.objectItems())
+			resolve(vi.elementItems())
+		}
+	}
+
+	resolve(m.RootItems)
+	for _, k := range m.definitionNames() {
+		resolve([]*VItem{m.Definitions[k]})
+	}
+
+	return unresolved
+}
+
+// refLoops tells whether the ref comes back to itself without checking
+// anything, through refs, alternatives and rules only, which would never
+// end.
+
+func refLoops(ref *VItem) bool {
+	seen := make(map[*VItem]bool)
+
+	var walk func(vi *VItem) bool
+	walk = func(vi *VItem) bool {
+		if vi == nil || seen[vi] {
+			return false
+		}
+		if vi == ref {
+			return true
+		}
+		seen[vi] = true
+
+		for _, svi := range vi.applied() {
+			if walk(svi) {
+				return true
+			}
+		}
+
+		switch vi.Type {
+		case V_TYPE_REF:
+			return walk(vi.Ref)
+		case V_TYPE_MULTI:
+			for _, svi := range vi.SubItems {
+				if walk(svi) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	return walk(ref.Ref)
+}
+
+func (m *Validator) definitionNames() []string {
+	names := make([]string, 0, len(m.Definitions))
+	for k := range m.Definitions {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SchemaRegistry holds Validators which refer to each other by name. It is
+// not safe to register while validating.
+
+type SchemaRegistry struct {
+	validators map[string]*Validator
+}
+
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		validators: make(map[string]*Validator),
+	}
+}
+
+// Register adds a compiled Validator under name and resolves the refs of
+// every registered Validator.
+
+func (m *SchemaRegistry) Register(name string, v *Validator) *SchemaRegistry {
+	v.registry = m
+	m.validators[name] = v
+
+	for _, rv := range m.validators {
+		rv.resolveRefs()
+	}
+
+	return m
+}
+
+func (m *SchemaRegistry) Get(name string) (*Validator, bool) {
+	v, ok := m.validators[name]
+	return v, ok
+}
+
+// Unresolved returns the refs not resolved yet, as "validator: ref".
+
+func (m *SchemaRegistry) Unresolved() []string {
+	names := make([]string, 0, len(m.validators))
+	for k := range m.validators {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	ret := make([]string, 0)
+	for _, name := range names {
+		for _, ref := range m.validators[name].resolveRefs() {
+			ret = append(ret, name+": "+ref)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,220 @@
+package djson
+
+import (
+	"strconv"
+)
+
+// Rules check a value beyond its type, with schemas applied to the value
+// itself:
+//
+//	"allOf": [...]            all of the schemas
+//	"oneOf": [...]            exactly one of them (an array of types is any one)
+//	"not": {...}              not the schema
+//	"if", "then", "else"      then if the value passes if, else otherwise
+//
+// and for objects:
+//
+//	"dependentRequired": {"cardNumber": ["expiry"]}
+//	"compare": [{"field": "endDate", "op": ">=", "other": "startDate"}]
+//
+// A missing dependent is VIOLATION_REQUIRED with the key which requires it
+// as Expected. A failed compare is VIOLATION_COMPARE at field with "op other"
+// as Expected; it is skipped if either is missing. VIOLATION_ONE_OF has the
+// number of schemas which pass as Expected, and VIOLATION_NOT the type of
+// not.
+
+var compareOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+type VDependency struct {
+	Key      string
+	Required []string
+}
+
+type VCompare struct {
+	Field string // a key or a path
+	Op    string // ==, !=, <, <=, > or >=
+	Other string // a key or a path
+}
+
+func (m *VItem) parseRules(ejson *DJSON) {
+	rule := func(key string) *VItem {
+		if rs, ok := ejson.Get(key); ok {
+			return GetVItem("__root__", rs)
+		}
+		return nil
+	}
+
+	rules := func(key string) []*VItem {
+		ret := make([]*VItem, 0)
+		if rs, ok := ejson.GetAsArray(key); ok {
+			for idx := 0; idx < rs.Length(); idx++ {
+				es, _ := rs.Get(idx)
+				ret = append(ret, GetVItem("__root__", es))
+			}
+		}
+		return ret
+	}
+
+	m.If, m.Then, m.Else, m.Not = rule("if"), rule("then"), rule("else"), rule("not")
+	m.AllOf, m.OneOf = rules("allOf"), rules("oneOf")
+
+	if deps, ok := ejson.GetAsObject("dependentRequired"); ok {
+		for _, k := range deps.GetKeys() {
+			dep := VDependency{Key: k}
+			if da, ok := deps.GetAsArray(k); ok {
+				for idx := 0; idx < da.Length(); idx++ {
+					dep.Required = append(dep.Required, da.GetAsString(idx))
+				}
+			}
+			m.DependentRequired = append(m.DependentRequired, dep)
+		}
+	}
+
+	if cmps, ok := ejson.GetAsArray("compare"); ok {
+		for idx := 0; idx < cmps.Length(); idx++ {
+			if cs, ok := cmps.GetAsObject(idx); ok {
+				m.Compare = append(m.Compare, VCompare{
+					Field: cs.GetAsString("field"),
+					Op:    cs.GetAsString("op"),
+					Other: cs.GetAsString("other"),
+				})
+			}
+		}
+	}
+}
+
+func (m *VItem) hasRules() bool {
+	return len(m.applied()) > 0 || len(m.DependentRequired) > 0 || len(m.Compare) > 0
+}
+
+// applied returns the schemas of the rules.
+
+func (m *VItem) applied() []*VItem {
+	ret := make([]*VItem, 0)
+	for _, vi := range []*VItem{m.If, m.Then, m.Else, m.Not} {
+		if vi != nil {
+			ret = append(ret, vi)
+		}
+	}
+	ret = append(ret, m.AllOf...)
+	return append(ret, m.OneOf...)
+}
+
+// rules checks the enum and the rules of vi if there is no violation after
+// before.
+
+func (m *vcheck) rules(vi *VItem, value *DJSON, path []interface{}, before int) {
+	if len(m.violations) > before {
+		return
+	}
+
+	actual := value.GetAsInterface()
+
+	if len(vi.Enum) > 0 && !value.IsObject() && !value.IsArray() && !enumMatch(vi, value) {
+		m.add(path, VIOLATION_ENUM, marshalString(NewArray().Put(vi.Enum), SerializeOptions{}), actual)
+		return
+	}
+
+	for _, svi := range vi.AllOf {
+		if m.item(svi, value, path); m.done() {
+			return
+		}
+	}
+
+	if len(vi.OneOf) > 0 {
+		passed := 0
+		for _, svi := range vi.OneOf {
+			if passes(svi, value) {
+				passed++
+			}
+		}
+		if passed != 1 {
+			m.add(path, VIOLATION_ONE_OF, strconv.Itoa(passed), actual)
+		}
+	}
+
+	if vi.Not != nil && passes(vi.Not, value) {
+		m.add(path, VIOLATION_NOT, vi.Not.TypeName, actual)
+	}
+
+	if vi.If != nil {
+		if passes(vi.If, value) {
+			if vi.Then != nil {
+				m.item(vi.Then, value, path)
+			}
+		} else if vi.Else != nil {
+			m.item(vi.Else, value, path)
+		}
+	}
+
+	if m.done() || !value.IsObject() {
+		return
+	}
+
+	for _, dep := range vi.DependentRequired {
+		if !value.HasKey(dep.Key) {
+			continue
+		}
+		for _, k := range dep.Required {
+			if !value.HasKey(k) {
+				m.add(appendPath(path, k), VIOLATION_REQUIRED, dep.Key, nil)
+			}
+		}
+	}
+
+	for _, cmp := range vi.Compare {
+		field, ok := value.getByTokens(keyOrPathTokens(cmp.Field)...)
+		if !ok {
+			continue
+		}
+		other, ok := value.getByTokens(keyOrPathTokens(cmp.Other)...)
+		if !ok {
+			continue
+		}
+
+		if !compareRule(field, other, cmp.Op) {
+			fpath := path
+			for _, token := range keyOrPathTokens(cmp.Field) {
+				fpath = appendPath(fpath, token)
+			}
+			m.add(fpath, VIOLATION_COMPARE, cmp.Op+" "+cmp.Other, field.GetAsInterface())
+		}
+	}
+}
+
+// passes checks the value against a schema of a rule, which is __root__.
+
+func passes(vi *VItem, value *DJSON) bool {
+	c := &vcheck{}
+	c.item(vi, value, nil)
+	return len(c.violations) == 0
+}
+
+// compareRule orders numbers and strings as queries do; other values only
+// compare by == and !=.
+
+func compareRule(a, b *DJSON, op string) bool {
+	cmp := compareValues(a.GetAsInterface(), b.GetAsInterface())
+
+	ordered := a.IsNumeric() && b.IsNumeric() || a.IsString() && b.IsString()
+	if !ordered && op != "==" && op != "!=" {
+		return false
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return false
+}
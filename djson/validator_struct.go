@@ -0,0 +1,322 @@
+package djson
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Structs are validated as the documents FromFields makes of them, so the
+// keys, and the paths of violations, are the json tags. The constraints of
+// a field are either in a compiled Validator or in its djson tag, which has
+// keywords of the syntax with scalar values:
+//
+//	Email string `json:"email" djson:"format=EMAIL,required"`
+//	Age   int    `json:"age" djson:"min=0,max=150"`
+//	Name  string `json:"name" djson:"type=NONEMPTY.STRING,max=20"`
+//
+// "format" is the type of a format, e.g. "EMAIL" or a registered one, and
+// without type the type is taken from the Go type: INT, UINT, NUMBER,
+// STRING, BOOL, OBJECT and ARRAY, with null.* as their values. Fields of
+// struct types, or slices of them, are checked by their own tags. Fields
+// without a json tag or a pointer are skipped, as FromFields does.
+
+// ValidateStruct returns every violation of the document FromFields makes
+// of st, a struct or a pointer to one.
+
+func (m *Validator) ValidateStruct(st interface{}) Violations {
+	rv := reflect.ValueOf(st)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr {
+		return m.Validate(nil)
+	}
+
+	return m.Validate(NewDJSON().FromFields(rv.Interface()))
+}
+
+// CompileStruct compiles the syntax the djson tags of st describe. It fails
+// with a *SchemaError, whose paths are in StructSyntax, if a tag is not
+// valid.
+
+func (m *Validator) CompileStruct(st interface{}) error {
+	return m.CompileE(StructSyntax(st).ToString())
+}
+
+var structValidators sync.Map // reflect.Type to *Validator or error
+
+// ValidateStruct validates st by its djson tags, compiled once for each
+// type.
+
+func ValidateStruct(st interface{}) (Violations, error) {
+	rt := reflect.TypeOf(st)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	cached, ok := structValidators.Load(rt)
+	if !ok {
+		v := NewValidator()
+		if err := v.CompileStruct(st); err != nil {
+			cached, _ = structValidators.LoadOrStore(rt, err)
+		} else {
+			cached, _ = structValidators.LoadOrStore(rt, v)
+		}
+	}
+
+	if err, ok := cached.(error); ok {
+		return nil, err
+	}
+
+	return cached.(*Validator).ValidateStruct(st), nil
+}
+
+// StructSyntax returns the Validator syntax the djson tags of st describe.
+// A struct type inside itself, as in a tree, is a definition named after the
+// type and referred to by "#Name".
+
+func StructSyntax(st interface{}) *DJSON {
+	rt := reflect.TypeOf(st)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	s := &structSchema{
+		expanding: make(map[reflect.Type]bool),
+		names:     make(map[reflect.Type]string),
+		defs:      NewObject(),
+	}
+
+	ret := NewObject().Put("type", "OBJECT").Put("object", NewObject())
+	if rt != nil && rt.Kind() == reflect.Struct {
+		ret.Put("object", s.object(rt))
+	}
+
+	if s.defs.Length() > 0 {
+		ret.Put("definitions", s.defs)
+	}
+
+	return NewDJSON().Put(ret)
+}
+
+// structSchema builds the syntax of struct types; expanding are those whose
+// fields are being built, and names those which are definitions.
+
+type structSchema struct {
+	expanding map[reflect.Type]bool
+	names     map[reflect.Type]string
+	defs      *DO
+}
+
+func (m *structSchema) object(rt reflect.Type) *DO {
+	if !m.expanding[rt] {
+		m.expanding[rt] = true
+		defer delete(m.expanding, rt)
+	}
+
+	ret := NewObject()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		key := field.Tag.Get("json")
+
+		if key == "" || field.Type.Kind() == reflect.Ptr || field.PkgPath != "" {
+			continue
+		}
+
+		if syntax := m.field(field.Type, field.Tag.Get("djson")); syntax != nil {
+			ret.Put(key, syntax)
+		}
+	}
+
+	return ret
+}
+
+// field returns nil if there is nothing to check.
+
+func (m *structSchema) field(rt reflect.Type, tag string) *DO {
+	ret := parseStructTag(tag)
+
+	switch {
+	case ret.HasKey("format"):
+		format, _ := ret.Get("format")
+		ret.Remove("format")
+		ret.Put("type", format)
+	case ret.HasKey("type"):
+	case tag == "" && !structNested(rt, make(map[reflect.Type]bool)):
+		return nil
+	default:
+		ret.Put("type", structType(rt))
+	}
+
+	switch ret.GetAsString("type") {
+	case "OBJECT":
+		if rt.Kind() != reflect.Struct || strings.HasPrefix(rt.String(), "null.") {
+			break
+		}
+		if m.expanding[rt] {
+			ret.Remove("type")
+			ret.Put("ref", "#"+m.define(rt))
+		} else {
+			ret.Put("object", m.object(rt))
+		}
+	case "ARRAY", "NONEMPTY.ARRAY":
+		if rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array {
+			if elem := m.field(rt.Elem(), ""); elem != nil {
+				ret.Put("array", elem)
+			}
+		}
+	}
+
+	return ret
+}
+
+// define puts the definition of rt once and returns its name, the name of
+// the type or, if another type has it, with a number.
+
+func (m *structSchema) define(rt reflect.Type) string {
+	if name, ok := m.names[rt]; ok {
+		return name
+	}
+
+	name := rt.Name()
+	if name == "" {
+		name = "struct"
+	}
+	for n := 2; m.defs.HasKey(name); n++ {
+		name = rt.Name() + strconv.Itoa(n)
+	}
+
+	m.names[rt] = name
+	m.defs.Put(name, NewObject()) // taken while its fields are built
+	m.defs.Put(name, NewObject().Put("type", "OBJECT").Put("object", m.object(rt)))
+
+	return name
+}
+
+// parseStructTag reads "key=value,key" with values as numbers, bools or
+// strings and a key alone as true. A value in single quotes is a string as
+// it is, commas included, e.g. regexp='^[a-z]{1,3}$'; it cannot have a
+// single quote.
+
+func parseStructTag(tag string) *DO {
+	ret := NewObject()
+
+	for _, kv := range splitStructTag(tag) {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+
+		idx := strings.Index(kv, "=")
+		if idx < 0 {
+			ret.Put(kv, true)
+			continue
+		}
+
+		k, v := strings.TrimSpace(kv[:idx]), strings.TrimSpace(kv[idx+1:])
+		if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+			ret.Put(k, v[1:len(v)-1])
+		} else if iv, err := strconv.ParseInt(v, 10, 64); err == nil {
+			ret.Put(k, iv)
+		} else if fv, err := strconv.ParseFloat(v, 64); err == nil {
+			ret.Put(k, fv)
+		} else if bv, err := strconv.ParseBool(v); err == nil {
+			ret.Put(k, bv)
+		} else {
+			ret.Put(k, v)
+		}
+	}
+
+	return ret
+}
+
+// splitStructTag splits the tag at the commas not in single quotes.
+
+func splitStructTag(tag string) []string {
+	ret := make([]string, 0)
+	quoted := false
+	start := 0
+
+	for idx := 0; idx < len(tag); idx++ {
+		switch tag[idx] {
+		case '\'':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				ret = append(ret, tag[start:idx])
+				start = idx + 1
+			}
+		}
+	}
+
+	return append(ret, tag[start:])
+}
+
+// structNested tells whether a field without a tag has fields with one;
+// seen are the types already looked into.
+
+func structNested(rt reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[rt] {
+		return false
+	}
+	seen[rt] = true
+
+	switch rt.Kind() {
+	case reflect.Slice, reflect.Array:
+		return structNested(rt.Elem(), seen)
+	case reflect.Struct:
+		if strings.HasPrefix(rt.String(), "null.") {
+			return false
+		}
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if field.Tag.Get("json") == "" || field.Type.Kind() == reflect.Ptr || field.PkgPath != "" {
+				continue
+			}
+			if field.Tag.Get("djson") != "" || structNested(field.Type, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// structType returns the type of the values FromFields makes of rt.
+
+func structType(rt reflect.Type) string {
+	if name := rt.String(); strings.HasPrefix(name, "null.") {
+		switch name = strings.TrimPrefix(name, "null."); {
+		case strings.HasPrefix(name, "Uint"):
+			return "UINT"
+		case strings.HasPrefix(name, "Int"):
+			return "INT"
+		case strings.HasPrefix(name, "Float"):
+			return "NUMBER"
+		case name == "String":
+			return "STRING"
+		case name == "Bool":
+			return "BOOL"
+		}
+	}
+
+	switch rt.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "INT"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "UINT"
+	case reflect.Float32, reflect.Float64:
+		return "NUMBER"
+	case reflect.String:
+		return "STRING"
+	case reflect.Bool:
+		return "BOOL"
+	case reflect.Slice, reflect.Array:
+		return "ARRAY"
+	}
+
+	return "OBJECT"
+}
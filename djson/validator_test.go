@@ -1,8 +1,12 @@
 package djson
 
 import (
+	"errors"
 	"log"
+	"strings"
 	"testing"
+
+	"github.com/volatiletech/null/v8"
 )
 
 func TestValidator1(t *testing.T) {
@@ -211,3 +215,990 @@ func TestValidator6(t *testing.T) {
 	}
 
 }
+
+func TestInferSchema(t *testing.T) {
+	samples := []*DJSON{
+		NewDJSON().Parse(`{"id": "2f1c9a4e-8b3d-4c7a-9e21-5d6f7a8b9c0d", "email": "gildong@example.com", "birth": "19900101", "age": 34, "score": 1.5, "tags": ["a", "bc"], "memo": "hi"}`),
+		NewDJSON().Parse(`{"id": "7c2b1a3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", "email": "chulsoo@example.co.kr", "birth": "2001-12-31", "age": 23, "score": 2, "tags": [], "vip": true}`),
+	}
+
+	schema := InferSchema(samples...)
+	log.Println(schema.ToString())
+
+	if schema.GetAsStringPath(`["object"]["id"]["type"]`) != "UUID" ||
+		schema.GetAsStringPath(`["object"]["email"]["type"]`) != "EMAIL" ||
+		schema.GetAsStringPath(`["object"]["birth"]["type"]`) != "YYYYMMDD" ||
+		schema.GetAsStringPath(`["object"]["age"]["type"]`) != "INT" ||
+		schema.GetAsStringPath(`["object"]["score"]["type"]`) != "NUMBER" ||
+		schema.GetAsStringPath(`["object"]["tags"]["array"]["type"]`) != "STRING" {
+		log.Fatal("wrong types")
+	}
+
+	if schema.GetAsIntPath(`["object"]["age"]["min"]`) != 23 || schema.GetAsIntPath(`["object"]["age"]["max"]`) != 34 {
+		log.Fatal("wrong min/max")
+	}
+
+	if !schema.GetAsBoolPath(`["object"]["age"]["required"]`) || schema.GetAsBoolPath(`["object"]["vip"]["required"]`) {
+		log.Fatal("wrong required")
+	}
+
+	dv := NewValidator()
+	if !dv.Compile(schema.ToString()) {
+		log.Fatal("must compile")
+	}
+
+	for _, sample := range samples {
+		if !dv.IsValid(sample) {
+			log.Fatal("sample must be valid: ", sample.ToString())
+		}
+	}
+
+	if dv.IsValid(NewDJSON().Parse(`{"id": "nope", "email": "a@b.com", "birth": "19900101", "age": 30, "score": 1}`)) {
+		log.Fatal("must not be valid")
+	}
+
+	if dv.IsValid(NewDJSON().Parse(`{"id": "2f1c9a4e-8b3d-4c7a-9e21-5d6f7a8b9c0d", "email": "a@b.com", "birth": "19900101", "score": 1}`)) {
+		log.Fatal("missing required key must not be valid")
+	}
+
+	// mixed types become alternatives
+	mixed := InferSchema(NewDJSON().Parse(`{"v": 1}`), NewDJSON().Parse(`{"v": "x"}`))
+	if v, _ := mixed.GetPath(`["object"]["v"]`); !v.IsArray() || v.Length() != 2 {
+		log.Fatal("must be alternatives: ", mixed.ToString())
+	}
+
+	// a key null in some sample is NULL or its type
+	nullable := []*DJSON{
+		NewDJSON().Parse(`{"memo": null, "note": null}`),
+		NewDJSON().Parse(`{"memo": "hi", "note": null}`),
+	}
+
+	schema = InferSchema(nullable...)
+	log.Println(schema.ToString())
+
+	if err := dv.CompileE(schema.ToString()); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, sample := range nullable {
+		if !dv.IsValid(sample) {
+			log.Fatal("sample must be valid: ", sample.ToString())
+		}
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"memo": "ok", "note": null}`)) {
+		log.Fatal("must be valid")
+	}
+
+	if dv.IsValid(NewDJSON().Parse(`{"memo": 1, "note": null}`)) || dv.IsValid(NewDJSON().Parse(`{"memo": null, "note": "x"}`)) {
+		log.Fatal("must not be valid")
+	}
+
+	if dv.IsValid(NewDJSON().Parse(`{"note": null}`)) {
+		log.Fatal("missing required nullable key must not be valid")
+	}
+}
+
+func TestValidatorNumber(t *testing.T) {
+	dv := NewValidator()
+	if err := dv.CompileE(`{"type": "OBJECT", "object": {"n": {"type": "NUMBER", "min": -1, "max": 10}, "f": "FLOAT"}}`); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, doc := range []string{`{"n": 3}`, `{"n": 3.5}`, `{"n": -1, "f": 1.5}`} {
+		if !dv.IsValid(NewDJSON().Parse(doc)) || len(dv.Validate(NewDJSON().Parse(doc))) > 0 {
+			log.Fatal("must be valid: ", doc)
+		}
+	}
+
+	for _, doc := range []string{`{"n": 11}`, `{"n": -1.5}`, `{"n": "3"}`, `{"f": 1}`} {
+		if dv.IsValid(NewDJSON().Parse(doc)) || len(dv.Validate(NewDJSON().Parse(doc))) != 1 {
+			log.Fatal("must not be valid: ", doc)
+		}
+	}
+}
+
+func TestValidatorNormalize(t *testing.T) {
+	dv := NewValidator()
+	dv.Compile(`{
+		"type": "OBJECT",
+		"object": {
+			"id": {"type": "INT", "required": true},
+			"price": {"type": "FLOAT"},
+			"name": {"type": "STRING"},
+			"active": {"type": "BOOL", "default": true},
+			"currency": {"type": "STRING", "default": "KRW"},
+			"tags": {"type": "ARRAY", "array": "STRING", "default": []},
+			"items": {
+				"type": "ARRAY",
+				"array": {"type": "OBJECT", "object": {"qty": {"type": "INT", "default": 1}}}
+			}
+		}
+	}`)
+
+	src := NewDJSON().Parse(`{"id": "42", "price": 10, "name": 1234, "active": "false", "items": [{"qty": "2"}, {"note": "x"}], "debug": true}`)
+
+	if dv.IsValid(src) {
+		log.Fatal("source must not be valid")
+	}
+
+	ret, report := dv.Normalize(src, NormalizeOptions{FillDefaults: true, Coerce: true, StripUnknown: true})
+	for _, c := range report.Changes {
+		log.Println(c.Path, c.Action, c.From, c.To)
+	}
+
+	expected := `{"active":false,"currency":"KRW","id":42,"items":[{"qty":2},{"qty":1}],"name":"1234","price":10,"tags":[]}`
+	if ret.ToString() != expected || !report.Valid {
+		log.Fatal("expected ", expected, " but ", ret.ToString())
+	}
+
+	if len(report.Changes) != 10 || report.Changes[0].Path != `["id"]` || report.Changes[0].Action != NORMALIZE_COERCE {
+		log.Fatal("wrong report")
+	}
+
+	if src.GetAsString("id") != "42" || !src.HasKey("debug") {
+		log.Fatal("source must not be changed")
+	}
+
+	// nothing but defaults
+	ret, report = dv.Normalize(NewDJSON().Parse(`{"id": 1, "x": 1}`), NormalizeOptions{FillDefaults: true})
+	if len(report.Changes) != 3 || !ret.HasKey("x") || !report.Valid {
+		log.Fatal("must only fill defaults: ", ret.ToString())
+	}
+}
+
+func TestValidatorValidate(t *testing.T) {
+	dv := NewValidator()
+	dv.Compile(`{
+		"type": "OBJECT",
+		"object": {
+			"id": {"type": "INT", "min": 1, "required": true},
+			"name": {"type": "STRING", "min": 2, "max": 10},
+			"email": {"type": "EMAIL"},
+			"code": {"type": "STRING", "regexp": "^[A-Z]{3}$"},
+			"items": {
+				"type": "ARRAY",
+				"array": {"type": "OBJECT", "object": {"qty": {"type": "INT", "max": 10, "required": true}}}
+			},
+			"value": ["INT", "UUID"]
+		}
+	}`)
+
+	valid := NewDJSON().Parse(`{"id": 1, "name": "Hong", "items": [{"qty": 1}], "value": 3}`)
+	if violations := dv.Validate(valid); len(violations) != 0 || !dv.IsValid(valid) {
+		log.Fatal("must be valid: ", violations)
+	}
+
+	invalid := NewDJSON().Parse(`{"name": "H", "email": "nope", "code": "abc", "items": [{"qty": 1}, {"qty": 11}, {}], "value": 1.5}`)
+
+	violations := dv.Validate(invalid)
+	out := violations.ToDJSON()
+	log.Println(out.ToString())
+
+	expected := []string{
+		`["id"] required INT`,
+		`["name"] min 2`,
+		`["email"] format EMAIL`,
+		`["code"] regexp ^[A-Z]{3}$`,
+		`["items"][1]["qty"] max 10`,
+		`["items"][2]["qty"] required INT`,
+		`["value"] no_match INT|UUID`,
+	}
+
+	if len(violations) != len(expected) || dv.IsValid(invalid) {
+		log.Fatal("wrong number of violations: ", len(violations))
+	}
+
+	for idx, v := range violations {
+		if v.Path+" "+v.Code+" "+v.Expected != expected[idx] {
+			log.Fatal("expected ", expected[idx], " but ", v)
+		}
+	}
+
+	if out.GetAsStringPath(`[1]["actual"]`) != "H" || out.GetAsStringPath(`[4]["path"]`) != `["items"][1]["qty"]` {
+		log.Fatal("wrong DJSON")
+	}
+}
+
+func TestValidatorCompileE(t *testing.T) {
+	dv := NewValidator()
+	if err := dv.CompileE(`{"type": "OBJECT", "object": {"name": {"type": "STRING", "min": 1, "max": 10}, "tags": {"type": "ARRAY", "array": ["INT", "EMAIL"]}}}`); err != nil {
+		log.Fatal(err)
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"name": "Hong", "tags": [1, "hong@lokks307.com"]}`)) {
+		log.Fatal("must be valid")
+	}
+
+	err := dv.CompileE(`{
+		"type": "OBJECT",
+		"object": {
+			"name": {"type": "STRNG"},
+			"age": {"type": "INT", "min": 10, "max": 1, "requird": true},
+			"code": {"type": "STRING", "regexp": "[a-"},
+			"email": {"type": "EMAIL", "max": 10},
+			"count": {"type": "INT", "required": "yes", "min": 1.5},
+			"tags": {"type": "ARRAY", "array": ["INT", "FLOT"]}
+		}
+	}`)
+
+	var serr *SchemaError
+	if !errors.As(err, &serr) {
+		log.Fatal("must fail")
+	}
+	log.Println(err)
+
+	expected := []string{
+		`["object"]["name"]["type"] unknown type`,
+		`["object"]["age"]["requird"] unknown keyword`,
+		`["object"]["age"] min > max`,
+		`["object"]["code"]["regexp"] invalid regexp`,
+		`["object"]["email"]["max"] unknown keyword`,
+		`["object"]["count"]["required"] wrong keyword type`,
+		`["object"]["count"]["min"] wrong keyword type`,
+		`["object"]["tags"]["array"][1] unknown type`,
+	}
+
+	if len(serr.Problems) != len(expected) {
+		log.Fatal("wrong number of problems: ", len(serr.Problems))
+	}
+
+	for idx, p := range serr.Problems {
+		if p.Path+" "+p.Code != expected[idx] {
+			log.Fatal("expected ", expected[idx], " but ", p.Path, " ", p.Code)
+		}
+	}
+
+	if err := dv.CompileE(`{"type": "INT"`); !errors.As(err, &serr) || serr.Problems[0].Code != SCHEMA_INVALID_JSON {
+		log.Fatal("must be invalid json")
+	}
+
+	if dv.Compile(`{"type": "OBJECT", "object": {"age": {"type": "INT", "requird": true}}}`) {
+		log.Fatal("unknown keyword must fail compile")
+	}
+
+	if dv.Compile(`{"type": "STRNG"}`) || dv.Compile(`{"type": "INT", "min": 10, "max": 1}`) {
+		log.Fatal("unknown type and min > max must fail compile")
+	}
+
+	if dv.Compile(`{"type": "OBJECT", "object": {"code": {"type": "STRING", "regexp": "[a-"}}}`) {
+		log.Fatal("invalid regexp must fail compile")
+	}
+
+	if dv.IsValid(NewDJSON().Parse(`{"code": "abc"}`)) || dv.IsValid(NewDJSON().Parse(`{"code": ""}`)) {
+		log.Fatal("invalid regexp must match nothing")
+	}
+}
+
+func TestValidatorJSONSchema(t *testing.T) {
+	schema := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"email": {"type": "string", "format": "email"},
+			"name": {"type": "string", "minLength": 1, "maxLength": 20, "pattern": "^[A-Z]"},
+			"age": {"type": "integer", "minimum": 0, "maximum": 150},
+			"status": {"enum": ["ACTIVE", "SUSPENDED"]},
+			"level": {"enum": [1, 2, 3]},
+			"contact": {"oneOf": [{"type": "string", "format": "email"}, {"type": "integer"}]},
+			"address": {"$ref": "#/$defs/address"},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+		},
+		"required": ["id", "name", "contact", "extra"],
+		"$defs": {
+			"address": {"properties": {"city": {"type": "string"}}, "required": ["city"]}
+		}
+	}`
+
+	syntax, err := ImportJSONSchema(schema)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(syntax.ToStringWith(SerializeOptions{}))
+
+	if err := NewValidator().CompileE(syntax.ToString()); err != nil {
+		log.Fatal(err)
+	}
+
+	dv := NewValidator()
+	if err := dv.CompileJSONSchema(schema); err != nil {
+		log.Fatal(err)
+	}
+
+	valid := `{"id": "1b4e28ba-2fa1-41d2-883f-0016d3cca427", "name": "Hong", "age": 30, "status": "ACTIVE", "level": 2,
+		"contact": 1234, "address": {"city": "Seoul"}, "tags": ["a", "b"], "extra": false}`
+	if !dv.IsValid(NewDJSON().Parse(valid)) {
+		log.Fatal(dv.Validate(NewDJSON().Parse(valid)))
+	}
+
+	invalid := map[string]string{
+		`["name"]`:            `{"name": "hong"}`,
+		`["age"]`:             `{"age": 151}`,
+		`["status"]`:          `{"status": "CLOSED"}`,
+		`["level"]`:           `{"level": 4}`,
+		`["contact"]`:         `{"contact": "hong"}`,
+		`["address"]["city"]`: `{"address": {}}`,
+		`["tags"]`:            `{"tags": ["a", "b", "c"]}`,
+		`["email"]`:           `{"email": "hong"}`,
+	}
+
+	for path, patch := range invalid {
+		doc := NewDJSON().Parse(valid)
+		pjson := NewDJSON().Parse(patch)
+		for _, k := range pjson.GetKeys() {
+			v, _ := pjson.Get(k)
+			doc.Put(k, v.GetAsInterface())
+		}
+
+		violations := dv.Validate(doc)
+		if len(violations) != 1 || violations[0].Path != path {
+			log.Fatal("expected violation at ", path, " but ", violations)
+		}
+	}
+
+	// "extra" is required but may be anything, null included
+	if doc := NewDJSON().Parse(valid).Put("extra", nil); !dv.IsValid(doc) {
+		log.Fatal("null extra must be valid ", dv.Validate(doc))
+	}
+	if v := dv.Validate(NewDJSON().Parse(valid).Remove("extra")); len(v) != 1 || v[0].Path != `["extra"]` || v[0].Code != VIOLATION_REQUIRED {
+		log.Fatal("expected required violation at [\"extra\"] but ", v)
+	}
+
+	tv := NewValidator()
+	if err := tv.CompileJSONSchema(`{"$defs": {"node": {"properties": {"name": {"type": "string"}, "children": {"items": {"$ref": "#/$defs/node"}}}}}, "$ref": "#/$defs/node"}`); err != nil {
+		log.Fatal(err)
+	}
+	if !tv.IsValid(NewDJSON().Parse(`{"name": "a", "children": [{"name": "b", "children": [{"name": "c"}]}]}`)) ||
+		tv.IsValid(NewDJSON().Parse(`{"name": "a", "children": [{"name": "b", "children": [{"name": 1}]}]}`)) {
+		log.Fatal("wrong recursive $ref")
+	}
+
+	_, err = ImportJSONSchema(`{"$ref": "https://example.com/schema.json"}`)
+	var serr *SchemaError
+	if !errors.As(err, &serr) || serr.Problems[0].Code != SCHEMA_UNSUPPORTED {
+		log.Fatal("external $ref must be unsupported")
+	}
+
+	// null is an alternative, and a format keeps its length bounds
+	nv := NewValidator()
+	if err := nv.CompileJSONSchema(`{"type": "object", "properties": {
+		"n": {"type": ["string", "null"]},
+		"e": {"enum": ["a", null]},
+		"mail": {"type": "string", "format": "email", "maxLength": 12}
+	}}`); err != nil {
+		log.Fatal(err)
+	}
+	if !nv.IsValid(NewDJSON().Parse(`{"n": null, "e": null, "mail": "hong@lk.com"}`)) || !nv.IsValid(NewDJSON().Parse(`{"n": "x", "e": "a"}`)) {
+		log.Fatal("null must be valid ", nv.Validate(NewDJSON().Parse(`{"n": null, "e": null, "mail": "hong@lk.com"}`)))
+	}
+	if len(nv.Validate(NewDJSON().Parse(`{"n": 1, "e": "b", "mail": "hong@lokks307.com"}`))) != 3 {
+		log.Fatal("must have 3 violations ", nv.Validate(NewDJSON().Parse(`{"n": 1, "e": "b", "mail": "hong@lokks307.com"}`)))
+	}
+	if nv.ToJSONSchema().GetTypePath(`["properties"]["n"]["anyOf"]`) != "array" {
+		log.Fatal("null must be exported")
+	}
+
+	// a required {} takes any value, a string of any length included
+	av := NewValidator()
+	if err := av.CompileJSONSchema(`{"type": "object", "properties": {"any": {}}, "required": ["any"]}`); err != nil {
+		log.Fatal(err)
+	}
+	for _, v := range []string{`"` + strings.Repeat("x", 10000) + `"`, `null`, `1.5`, `true`, `{"a": 1}`, `[1]`} {
+		if doc := NewDJSON().Parse(`{"any": ` + v + `}`); !av.IsValid(doc) {
+			log.Fatal("any value must be valid ", av.Validate(doc))
+		}
+	}
+	if av.IsValid(NewDJSON().Parse(`{}`)) {
+		log.Fatal("required any must be present")
+	}
+
+	// export and import back
+	lv := NewValidator()
+	lv.Compile(`{"type": "OBJECT", "object": {
+		"id": {"type": "UUID", "required": true},
+		"count": {"type": "UINT", "max": 10, "default": 1},
+		"birth": "YYYYMMDD",
+		"items": {"type": "ARRAY", "array": ["INT", "HEX"], "required": true}
+	}}`)
+
+	exported := lv.ToJSONSchema()
+	log.Println(exported.ToStringWith(SerializeOptions{}))
+
+	if exported.GetAsString("$schema") != JSON_SCHEMA_DRAFT || exported.GetAsIntPath(`["properties"]["count"]["maximum"]`) != 10 {
+		log.Fatal("wrong export")
+	}
+
+	if r, _ := exported.Get("required"); !r.Equal(NewDJSON().Parse(`["id", "items"]`)) {
+		log.Fatal("wrong required")
+	}
+
+	rv := NewValidator()
+	if err := rv.CompileJSONSchema(exported.ToString()); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, doc := range []string{
+		`{"id": "1b4e28ba-2fa1-41d2-883f-0016d3cca427", "items": [1, "0a"]}`,
+		`{"id": "1b4e28ba-2fa1-41d2-883f-0016d3cca427", "items": [1, "0a"], "count": 11}`,
+		`{"id": "1b4e28ba-2fa1-41d2-883f-0016d3cca427", "items": ["0g"]}`,
+		`{"id": "1b4e28ba-2fa1-41d2-883f-0016d3cca427", "items": [], "birth": "2024-01-31"}`,
+		`{"id": "1b4e28ba-2fa1-41d2-883f-0016d3cca427", "items": [], "birth": "20241301"}`,
+		`{"items": []}`,
+	} {
+		tjson := NewDJSON().Parse(doc)
+		if lv.IsValid(tjson) != rv.IsValid(tjson) {
+			log.Fatal("export differs for ", doc)
+		}
+	}
+}
+
+func TestValidatorEnum(t *testing.T) {
+	dv := NewValidator()
+	if err := dv.CompileE(`{"type": "OBJECT", "object": {
+		"status": {"type": "STRING", "enum": ["ACTIVE", "SUSPENDED", "CLOSED"]},
+		"grade": {"type": "STRING", "enum": ["gold", "silver"], "ignoreCase": true},
+		"level": {"type": "INT", "enum": [1, 2, 3]},
+		"rate": {"type": "NUMBER", "enum": [0.5, 1]},
+		"agree": {"type": "BOOL", "const": true, "required": true},
+		"country": {"type": "ISO31661A2", "enum": ["KR", "US"]}
+	}}`); err != nil {
+		log.Fatal(err)
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"status": "ACTIVE", "grade": "GOLD", "level": 2, "rate": 1.0, "agree": true, "country": "KR"}`)) {
+		log.Fatal("must be valid")
+	}
+
+	violations := dv.Validate(NewDJSON().Parse(`{"status": "active", "grade": "bronze", "level": 4, "rate": 0.7, "agree": false, "country": "JP"}`))
+	log.Println(violations)
+
+	expected := []string{`["status"]`, `["grade"]`, `["level"]`, `["rate"]`, `["agree"]`, `["country"]`}
+	if len(violations) != len(expected) {
+		log.Fatal("wrong number of violations")
+	}
+	for idx, v := range violations {
+		if v.Path != expected[idx] || v.Code != VIOLATION_ENUM {
+			log.Fatal("wrong violation ", v)
+		}
+	}
+
+	if violations[0].Expected != `["ACTIVE","SUSPENDED","CLOSED"]` {
+		log.Fatal("wrong expected ", violations[0].Expected)
+	}
+
+	exported := dv.ToJSONSchema()
+	if exported.GetTypePath(`["properties"]["status"]["enum"]`) != "array" || exported.GetTypePath(`["properties"]["grade"]["enum"]`) != "" {
+		log.Fatal("wrong export ", exported.ToString())
+	}
+
+	// the type is checked first
+	if v := dv.Validate(NewDJSON().Parse(`{"level": "1", "agree": true}`)); len(v) != 1 || v[0].Code != VIOLATION_TYPE {
+		log.Fatal("must be a type violation")
+	}
+
+	err := NewValidator().CompileE(`{"type": "OBJECT", "object": {
+		"level": {"type": "INT", "enum": [1, "2"]},
+		"tags": {"type": "ARRAY", "enum": [1]},
+		"name": {"type": "STRING", "enum": []}
+	}}`)
+
+	var serr *SchemaError
+	if !errors.As(err, &serr) || len(serr.Problems) != 3 {
+		log.Fatal("must have 3 problems: ", err)
+	}
+	if serr.Problems[0].Path != `["object"]["level"]["enum"][1]` || serr.Problems[1].Code != SCHEMA_UNKNOWN_KEYWORD {
+		log.Fatal("wrong problems: ", err)
+	}
+}
+
+func TestValidatorRef(t *testing.T) {
+	dv := NewValidator()
+	if err := dv.CompileE(`{
+		"type": "OBJECT",
+		"object": {
+			"home": {"ref": "#address", "required": true},
+			"office": {"ref": "#address"},
+			"menu": {"ref": "#tree"}
+		},
+		"definitions": {
+			"address": {"type": "OBJECT", "object": {"city": {"type": "STRING", "required": true}, "zip": "DEC"}},
+			"tree": {"type": "OBJECT", "object": {"name": "STRING", "children": {"type": "ARRAY", "array": {"ref": "#tree"}}}}
+		}
+	}`); err != nil {
+		log.Fatal(err)
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"home": {"city": "Seoul"}, "menu": {"name": "a", "children": [{"name": "b", "children": [{"name": "c"}]}]}}`)) {
+		log.Fatal("must be valid")
+	}
+
+	violations := dv.Validate(NewDJSON().Parse(`{"office": {"zip": "x"}, "menu": {"children": [{"children": [{"name": 1}]}]}}`))
+	log.Println(violations)
+
+	expected := []string{
+		`["home"] required`,
+		`["office"]["city"] required`,
+		`["office"]["zip"] format`,
+		`["menu"]["children"][0]["children"][0]["name"] type`,
+	}
+	if len(violations) != len(expected) {
+		log.Fatal("wrong number of violations")
+	}
+	for idx, v := range violations {
+		if v.Path+" "+v.Code != expected[idx] {
+			log.Fatal("expected ", expected[idx], " but ", v)
+		}
+	}
+
+	schema := dv.ToJSONSchema()
+	if schema.GetAsStringPath(`["properties"]["menu"]["$ref"]`) != "#/$defs/tree" || !schema.HasKeys("$defs") {
+		log.Fatal("wrong export ", schema.ToString())
+	}
+
+	err := NewValidator().CompileE(`{"type": "OBJECT", "object": {
+		"a": {"ref": "#nothing"},
+		"b": {"ref": "#loop"},
+		"c": {"ref": "#address", "min": 1}
+	}, "definitions": {"loop": ["INT", {"ref": "#loop"}], "address": "STRING"}}`)
+
+	var serr *SchemaError
+	if !errors.As(err, &serr) || len(serr.Problems) != 2 ||
+		serr.Problems[0].Code != SCHEMA_UNKNOWN_REF || serr.Problems[1].Code != SCHEMA_UNKNOWN_KEYWORD {
+		log.Fatal("wrong problems: ", err)
+	}
+
+	// registered validators refer to each other
+	user := NewValidator()
+	user.Compile(`{"type": "OBJECT", "object": {"name": "NONEMPTY.STRING", "company": {"ref": "company"}}}`)
+
+	company := NewValidator()
+	company.Compile(`{"type": "OBJECT", "object": {"name": "STRING", "ceo": {"ref": "user"}, "hq": {"ref": "common#address"}}}`)
+
+	registry := NewSchemaRegistry().Register("user", user).Register("company", company)
+
+	if unresolved := registry.Unresolved(); len(unresolved) != 1 || unresolved[0] != "company: common#address" {
+		log.Fatal("wrong unresolved ", unresolved)
+	}
+
+	common := NewValidator()
+	common.Compile(`{"type": "OBJECT", "object": {}, "definitions": {"address": {"type": "OBJECT", "object": {"city": {"type": "STRING", "required": true}}}}}`)
+	registry.Register("common", common)
+
+	if len(registry.Unresolved()) != 0 {
+		log.Fatal("must be resolved")
+	}
+
+	if !user.IsValid(NewDJSON().Parse(`{"name": "Hong", "company": {"ceo": {"name": "Kim"}, "hq": {"city": "Seoul"}}}`)) {
+		log.Fatal("must be valid")
+	}
+
+	if v := user.Validate(NewDJSON().Parse(`{"name": "Hong", "company": {"ceo": {"name": ""}, "hq": {}}}`)); len(v) != 2 ||
+		v[0].Path != `["company"]["ceo"]["name"]` || v[1].Path != `["company"]["hq"]["city"]` {
+		log.Fatal("wrong violations ", v)
+	}
+}
+
+func TestValidatorRules(t *testing.T) {
+	dv := NewValidator()
+	if err := dv.CompileE(`{
+		"type": "OBJECT",
+		"object": {
+			"type": {"type": "STRING", "enum": ["CARD", "BANK"], "required": true},
+			"cardNumber": "DEC",
+			"account": "STRING",
+			"startDate": "YYYYMMDD",
+			"endDate": "YYYYMMDD",
+			"email": "EMAIL",
+			"phone": "TELEPHONE",
+			"password": "STRING",
+			"confirm": "STRING"
+		},
+		"if": {"type": "OBJECT", "object": {"type": {"type": "STRING", "const": "CARD", "required": true}}},
+		"then": {"type": "OBJECT", "object": {"cardNumber": {"type": "DEC", "required": true}}},
+		"else": {"type": "OBJECT", "object": {"account": {"type": "STRING", "required": true}}},
+		"oneOf": [
+			{"type": "OBJECT", "object": {"email": {"type": "EMAIL", "required": true}}},
+			{"type": "OBJECT", "object": {"phone": {"type": "TELEPHONE", "required": true}}}
+		],
+		"not": {"type": "OBJECT", "object": {"test": {"type": "BOOL", "const": true, "required": true}}},
+		"dependentRequired": {"password": ["confirm"]},
+		"compare": [
+			{"field": "endDate", "op": ">=", "other": "startDate"},
+			{"field": "confirm", "op": "==", "other": "password"}
+		]
+	}`); err != nil {
+		log.Fatal(err)
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"type": "CARD", "cardNumber": "1234", "email": "hong@lokks307.com",
+		"startDate": "20240101", "endDate": "20241231", "password": "secret", "confirm": "secret"}`)) {
+		log.Fatal("must be valid")
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"type": "BANK", "account": "1234", "phone": "010-1234-5678", "test": false}`)) {
+		log.Fatal("must be valid")
+	}
+
+	cases := map[string]string{
+		`{"type": "CARD", "email": "hong@lokks307.com"}`:                                                             `["cardNumber"] required`,
+		`{"type": "BANK", "email": "hong@lokks307.com"}`:                                                             `["account"] required`,
+		`{"type": "BANK", "account": "1"}`:                                                                           ` one_of`,
+		`{"type": "BANK", "account": "1", "email": "hong@lokks307.com", "phone": "010-1234-5678"}`:                   ` one_of`,
+		`{"type": "BANK", "account": "1", "phone": "010-1234-5678", "test": true}`:                                   ` not`,
+		`{"type": "BANK", "account": "1", "phone": "010-1234-5678", "password": "a"}`:                                `["confirm"] required`,
+		`{"type": "BANK", "account": "1", "phone": "010-1234-5678", "password": "a", "confirm": "b"}`:                `["confirm"] compare`,
+		`{"type": "BANK", "account": "1", "phone": "010-1234-5678", "startDate": "20240102", "endDate": "20240101"}`: `["endDate"] compare`,
+	}
+
+	for doc, expected := range cases {
+		violations := dv.Validate(NewDJSON().Parse(doc))
+		if len(violations) != 1 || violations[0].Path+" "+violations[0].Code != expected {
+			log.Fatal(doc, " expected ", expected, " but ", violations)
+		}
+	}
+
+	err := NewValidator().CompileE(`{"type": "OBJECT", "object": {},
+		"oneOf": [],
+		"compare": [{"field": "a", "op": "=>", "other": "b"}],
+		"dependentRequired": {"a": "b"}
+	}`)
+
+	var serr *SchemaError
+	if !errors.As(err, &serr) || len(serr.Problems) != 3 {
+		log.Fatal("must have 3 problems: ", err)
+	}
+
+	// JSON Schema has the same rules but compare
+	schema := dv.ToJSONSchema()
+	if !schema.HasKeys("if") || !schema.HasKeys("oneOf") || !schema.HasKeys("dependentRequired") || schema.HasKeys("compare") {
+		log.Fatal("wrong export ", schema.ToString())
+	}
+
+	rv := NewValidator()
+	if err := rv.CompileJSONSchema(schema.ToString()); err != nil {
+		log.Fatal(err)
+	}
+
+	for doc, expected := range cases {
+		if !strings.HasSuffix(expected, "compare") && rv.IsValid(NewDJSON().Parse(doc)) {
+			log.Fatal("imported must be invalid: ", doc)
+		}
+	}
+}
+
+func TestValidatorObjectRules(t *testing.T) {
+	dv := NewValidator()
+	if err := dv.CompileE(`{
+		"type": "OBJECT",
+		"object": {
+			"name": {"type": "STRING", "required": true},
+			"labels": {
+				"type": "OBJECT",
+				"patternProperties": {"^x-": "STRING", "^n-": "INT"},
+				"additional": false,
+				"maxProperties": 3
+			},
+			"scores": {
+				"type": "OBJECT",
+				"propertyNames": {"type": "STRING", "regexp": "^[a-z]+$"},
+				"additional": "NUMBER",
+				"minProperties": 1
+			}
+		},
+		"additional": false
+	}`); err != nil {
+		log.Fatal(err)
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"name": "a", "labels": {"x-a": "1", "n-b": 2}, "scores": {"math": 90, "art": 85.5}}`)) {
+		log.Fatal("must be valid")
+	}
+
+	violations := dv.Validate(NewDJSON().Parse(`{"name": "a", "admin": true,
+		"labels": {"x-a": 1, "y": "2", "n-a": 1, "n-b": 2},
+		"scores": {"Math": 90, "art": "A"}}`))
+	log.Println(violations)
+
+	expected := []string{
+		`["labels"] max`,
+		`["labels"]["x-a"] type`,
+		`["labels"]["y"] additional`,
+		`["scores"]["Math"] property_name`,
+		`["scores"]["art"] type`,
+		`["admin"] additional`,
+	}
+	if len(violations) != len(expected) {
+		log.Fatal("wrong number of violations")
+	}
+	for idx, v := range violations {
+		if v.Path+" "+v.Code != expected[idx] {
+			log.Fatal("expected ", expected[idx], " but ", v)
+		}
+	}
+
+	if v := dv.Validate(NewDJSON().Parse(`{"name": "a", "scores": {}}`)); len(v) != 1 || v[0].Code != VIOLATION_MIN {
+		log.Fatal("must be min ", v)
+	}
+
+	// normalize keeps the keys of patterns
+	ret, _ := dv.Normalize(NewDJSON().Parse(`{"name": "a", "admin": true, "labels": {"x-a": "1", "y": "2"}}`), NormalizeOptions{StripUnknown: true})
+	if ret.HasKey("admin") || ret.GetAsStringPath(`["labels"]["x-a"]`) != "1" || ret.GetTypePath(`["labels"]["y"]`) != "" {
+		log.Fatal("wrong normalize ", ret.ToString())
+	}
+
+	rv := NewValidator()
+	if err := rv.CompileJSONSchema(dv.ToJSONSchema().ToString()); err != nil {
+		log.Fatal(err)
+	}
+	if len(rv.Validate(NewDJSON().Parse(`{"name": "a", "admin": true, "labels": {"y": "2"}, "scores": {"Math": 90}}`))) != 3 {
+		log.Fatal("imported must have the same rules")
+	}
+
+	err := NewValidator().CompileE(`{"type": "OBJECT", "patternProperties": {"[": "STRING"}, "minProperties": 3, "maxProperties": 1}`)
+	var serr *SchemaError
+	if !errors.As(err, &serr) || len(serr.Problems) != 2 {
+		log.Fatal("must have 2 problems: ", err)
+	}
+}
+
+func TestValidatorArrayRules(t *testing.T) {
+	dv := NewValidator()
+	if err := dv.CompileE(`{
+		"type": "OBJECT",
+		"object": {
+			"point": {"type": "ARRAY", "prefixItems": ["STRING", "INT"], "array": "NUMBER"},
+			"tags": {"type": "ARRAY", "array": "STRING", "uniqueItems": true},
+			"users": {"type": "ARRAY", "array": {"type": "OBJECT", "object": {"id": "INT"}}, "uniqueBy": "id"},
+			"roles": {"type": "ARRAY", "array": "STRING", "contains": {"type": "STRING", "enum": ["admin"]}, "maxContains": 1}
+		}
+	}`); err != nil {
+		log.Fatal(err)
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"point": ["a", 1, 2.5, 3], "tags": ["a", "b"], "users": [{"id": 1}, {"id": 2}, {}], "roles": ["user", "admin"]}`)) {
+		log.Fatal("must be valid")
+	}
+
+	violations := dv.Validate(NewDJSON().Parse(`{"point": [1, 1, "x"], "tags": ["a", "b", "a"], "users": [{"id": 1}, {"id": 2}, {"id": 1}], "roles": ["user"]}`))
+	log.Println(violations)
+
+	expected := []string{
+		`["point"][0] type`,
+		`["point"][2] type`,
+		`["tags"][2] unique`,
+		`["users"][2]["id"] unique`,
+		`["roles"] contains`,
+	}
+	if len(violations) != len(expected) {
+		log.Fatal("wrong number of violations")
+	}
+	for idx, v := range violations {
+		if v.Path+" "+v.Code != expected[idx] {
+			log.Fatal("expected ", expected[idx], " but ", v)
+		}
+	}
+	if violations[2].Expected != "0" || violations[4].Expected != ">=1" {
+		log.Fatal("wrong expected ", violations)
+	}
+
+	if v := dv.Validate(NewDJSON().Parse(`{"roles": ["admin", "admin"]}`)); len(v) != 1 || v[0].Expected != "<=1" {
+		log.Fatal("must be too many ", v)
+	}
+
+	rv := NewValidator()
+	if err := rv.CompileJSONSchema(dv.ToJSONSchema().ToString()); err != nil {
+		log.Fatal(err)
+	}
+	if len(rv.Validate(NewDJSON().Parse(`{"point": [1, 1], "tags": ["a", "a"], "roles": []}`))) != 3 {
+		log.Fatal("imported must have the same rules")
+	}
+
+	// items false ends the tuple
+	if err := rv.CompileJSONSchema(`{"type": "array", "prefixItems": [{"type": "string"}], "items": false}`); err != nil {
+		log.Fatal(err)
+	}
+	if rv.IsValid(NewDJSON().Parse(`["a", "b"]`)) {
+		log.Fatal("must be too long")
+	}
+
+	err := NewValidator().CompileE(`{"type": "ARRAY", "prefixItems": [], "uniqueItems": "yes", "minContains": 3, "maxContains": 1}`)
+	var serr *SchemaError
+	if !errors.As(err, &serr) || len(serr.Problems) != 3 {
+		log.Fatal("must have 3 problems: ", err)
+	}
+}
+
+// Korean business registration number, e.g. 220-81-62517
+func checkKRBRN(ts string, vi ...int64) bool {
+	digits := strings.Replace(ts, "-", "", -1)
+	if len(digits) != 10 || strings.Trim(digits, "0123456789") != "" {
+		return false
+	}
+
+	sum := 0
+	for idx, w := range []int{1, 3, 7, 1, 3, 7, 1, 3, 5} {
+		sum += int(digits[idx]-'0') * w
+	}
+	sum += int(digits[8]-'0') * 5 / 10
+
+	return (10-sum%10)%10 == int(digits[9]-'0')
+}
+
+func TestValidatorFormat(t *testing.T) {
+	if err := RegisterFormat("lokks:KR_BRN", checkKRBRN); err != nil {
+		log.Fatal(err)
+	}
+	if RegisterFormat("lokks:KR_BRN", checkKRBRN) == nil || RegisterFormat("KR_BRN", checkKRBRN) == nil {
+		log.Fatal("must not register")
+	}
+
+	dv := NewValidator()
+	if err := dv.CompileE(`{
+		"type": "OBJECT",
+		"object": {
+			"brn": {"type": "lokks:KR_BRN", "required": true},
+			"at": "DATETIME",
+			"ip": ["IPV4", "IPV6"],
+			"home": "URI",
+			"ttl": "DURATION"
+		}
+	}`); err != nil {
+		log.Fatal(err)
+	}
+
+	if !dv.IsValid(NewDJSON().Parse(`{"brn": "220-81-62574", "at": "2024-01-02T15:04:05.5+09:00", "ip": "::1", "home": "https://lokks307.com/a?b=c", "ttl": "P1DT2H"}`)) {
+		log.Fatal("must be valid")
+	}
+
+	violations := dv.Validate(NewDJSON().Parse(`{"brn": "220-81-62575", "at": "2024-01-02 15:04:05Z", "ip": "1.2.3", "home": "lokks307.com", "ttl": "P1H"}`))
+	log.Println(violations)
+
+	expected := []string{
+		`["brn"] format`,
+		`["at"] format`,
+		`["ip"] no_match`,
+		`["home"] format`,
+		`["ttl"] format`,
+	}
+	if len(violations) != len(expected) {
+		log.Fatal("wrong number of violations")
+	}
+	for idx, v := range violations {
+		if v.Path+" "+v.Code != expected[idx] {
+			log.Fatal("expected ", expected[idx], " but ", v)
+		}
+	}
+
+	if v := dv.Validate(NewDJSON().Parse(`{"brn": "2208162574", "at": "2024-13-02T15:04:05Z", "ttl": "P1DT"}`)); len(v) != 2 {
+		log.Fatal("must be 2 violations ", v)
+	}
+
+	rv := NewValidator()
+	if err := rv.CompileJSONSchema(dv.ToJSONSchema().ToString()); err != nil {
+		log.Fatal(err)
+	}
+	if rv.ToJSONSchema().GetAsStringPath(`["properties"]["brn"]["format"]`) != "lokks:KR_BRN" {
+		log.Fatal("registered format must be exported by its name")
+	}
+	if len(rv.Validate(NewDJSON().Parse(`{"brn": "220-81-62575", "at": "2024-01-02 15:04:05Z", "ip": "1.2.3"}`))) != 3 {
+		log.Fatal("imported must have the same formats")
+	}
+
+	err := NewValidator().CompileE(`{"type": "OBJECT", "object": {"a": "lokks:NONE", "b": {"type": "DATETIME", "max": 10}}}`)
+	var serr *SchemaError
+	if !errors.As(err, &serr) || len(serr.Problems) != 2 {
+		log.Fatal("must have 2 problems: ", err)
+	}
+}
+
+type testStructItem struct {
+	Sku string `json:"sku" djson:"type=NONEMPTY.STRING,max=8"`
+	Qty int    `json:"qty" djson:"min=1,max=10"`
+}
+
+type testStructOrder struct {
+	Id      string           `json:"id" djson:"format=UUID,required"`
+	Email   null.String      `json:"email" djson:"format=EMAIL"`
+	Rate    float64          `json:"rate" djson:"min=0,max=1"`
+	Items   []testStructItem `json:"items" djson:"type=NONEMPTY.ARRAY"`
+	Address struct {
+		City string `json:"city" djson:"max=4"`
+	} `json:"address"`
+	Note string `json:"note"`
+}
+
+func TestValidatorStruct(t *testing.T) {
+	order := testStructOrder{
+		Id:    "7b5e7c5a-3c4f-4b8e-9a53-2f8b8c7e1f10",
+		Email: null.StringFrom("hong@lokks307.com"),
+		Rate:  0.5,
+		Items: []testStructItem{{Sku: "A-1", Qty: 2}},
+	}
+	order.Address.City = "SEL"
+
+	if v, err := ValidateStruct(&order); err != nil || len(v) != 0 {
+		log.Fatal("must be valid ", v, err)
+	}
+
+	order.Email = null.StringFrom("nope")
+	order.Rate = 1.5
+	order.Items = append(order.Items, testStructItem{Sku: "", Qty: 11})
+	order.Address.City = "Seoul"
+
+	violations, err := ValidateStruct(order)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(violations)
+
+	expected := map[string]string{
+		`["email"]`:           VIOLATION_FORMAT,
+		`["rate"]`:            VIOLATION_MAX,
+		`["items"][1]["sku"]`: VIOLATION_MIN,
+		`["items"][1]["qty"]`: VIOLATION_MAX,
+		`["address"]["city"]`: VIOLATION_MAX,
+	}
+	if len(violations) != len(expected) {
+		log.Fatal("wrong number of violations")
+	}
+	for _, v := range violations {
+		if expected[v.Path] != v.Code {
+			log.Fatal("unexpected ", v)
+		}
+	}
+
+	// the same as a compiled Validator and a FromFields result
+	dv := NewValidator()
+	if err := dv.CompileStruct(testStructOrder{}); err != nil {
+		log.Fatal(err)
+	}
+	if len(dv.ValidateStruct(&order)) != len(expected) || len(dv.Validate(NewDJSON().FromFields(order))) != len(expected) {
+		log.Fatal("must have the same violations")
+	}
+	if StructSyntax(order).GetTypePath(`["object"]["note"]`) != "" {
+		log.Fatal("a field without djson tag must not be checked")
+	}
+
+	// a tree refers to its own definition
+	type testStructNode struct {
+		Name     string           `json:"name" djson:"regexp='^[a-z]{1,3}$'"`
+		Children []testStructNode `json:"children"`
+	}
+	tree := testStructNode{Name: "a", Children: []testStructNode{{Name: "b"}, {Name: "c", Children: []testStructNode{{Name: "dddd"}}}}}
+	if v, err := ValidateStruct(tree); err != nil || len(v) != 1 || v[0].Path != `["children"][1]["children"][0]["name"]` || v[0].Code != VIOLATION_REGEXP {
+		log.Fatal("wrong tree violations ", v, err)
+	}
+	if StructSyntax(tree).GetAsStringPath(`["object"]["children"]["array"]["ref"]`) != "#testStructNode" {
+		log.Fatal("wrong tree syntax ", StructSyntax(tree).ToString())
+	}
+
+	type badStruct struct {
+		Age int `json:"age" djson:"min=10,max=1,requird"`
+	}
+	_, err = ValidateStruct(badStruct{})
+	var serr *SchemaError
+	if !errors.As(err, &serr) || len(serr.Problems) != 2 {
+		log.Fatal("must have 2 problems: ", err)
+	}
+}
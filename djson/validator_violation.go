@@ -0,0 +1,379 @@
+package djson
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	VIOLATION_REQUIRED = "required"
+	VIOLATION_TYPE     = "type"
+	VIOLATION_MIN      = "min"
+	VIOLATION_MAX      = "max"
+	VIOLATION_REGEXP   = "regexp"
+	VIOLATION_FORMAT   = "format"
+	VIOLATION_ENUM     = "enum"
+	VIOLATION_NO_MATCH = "no_match" // none of the alternatives
+	VIOLATION_ONE_OF   = "one_of"   // not exactly one of oneOf
+	VIOLATION_NOT      = "not"
+	VIOLATION_COMPARE  = "compare"
+	VIOLATION_SCHEMA   = "schema" // the syntax itself is broken
+
+	VIOLATION_ADDITIONAL    = "additional"    // a key not declared
+	VIOLATION_PROPERTY_NAME = "property_name" // a key not matching propertyNames
+	VIOLATION_UNIQUE        = "unique"        // an element equal to an earlier one
+	VIOLATION_CONTAINS      = "contains"
+)
+
+// Violation is a reason a document is not valid. Expected is the type name
+// in the syntax (e.g. "INT", "EMAIL") for VIOLATION_TYPE and
+// VIOLATION_FORMAT, the bound for VIOLATION_MIN and VIOLATION_MAX (a length
+// for STRING and ARRAY), the pattern for VIOLATION_REGEXP, the alternatives
+// joined with | for VIOLATION_NO_MATCH and the allowed values as a JSON
+// array for VIOLATION_ENUM. Actual is the value, nil if it is missing. Rules
+// have their own; see validator_rules.go, validator_object.go and
+// validator_array.go.
+
+type Violation struct {
+	Path     string
+	Code     string
+	Expected string
+	Actual   interface{}
+}
+
+type Violations []Violation
+
+// ToDJSON returns the violations as an array of
+// {"path", "code", "expected", "actual"}.
+
+func (m Violations) ToDJSON() *DJSON {
+	ret := NewDJSON(JSON_ARRAY)
+	for _, v := range m {
+		ret.Array.PushBack(NewObject().
+			Put("path", v.Path).
+			Put("code", v.Code).
+			Put("expected", v.Expected).
+			Put("actual", cloneValue(v.Actual)))
+	}
+	return ret
+}
+
+// Validate returns every violation of tjson, none if it is valid. It has
+// the same rules as IsValid.
+
+func (m *Validator) Validate(tjson *DJSON) Violations {
+	c := &vcheck{all: true}
+
+	if tjson == nil {
+		if len(m.RootItems) > 0 {
+			c.add(nil, VIOLATION_REQUIRED, "", nil)
+		}
+		return c.violations
+	}
+
+	if m.Syntax.IsObject() {
+		if len(m.RootItems) > 0 {
+			c.item(m.RootItems[0], tjson, nil)
+		}
+	} else if m.Syntax.IsArray() || m.Syntax.IsString() {
+		c.alternatives(m.RootItems, tjson, nil, nil, tjson)
+	}
+
+	return c.violations
+}
+
+// vcheck is CheckVItem collecting violations; unless all, it stops at the
+// first.
+
+type vcheck struct {
+	all        bool
+	violations Violations
+}
+
+func (m *vcheck) add(path []interface{}, code, expected string, actual interface{}) {
+	m.violations = append(m.violations, Violation{
+		Path:     BuildPath(path...),
+		Code:     code,
+		Expected: expected,
+		Actual:   actual,
+	})
+}
+
+func (m *vcheck) done() bool {
+	return !m.all && len(m.violations) > 0
+}
+
+// item checks vi against tjson itself (__root__, __array__) or against the
+// key vi.Name of tjson; path is that of tjson.
+
+func (m *vcheck) item(vi *VItem, tjson *DJSON, path []interface{}) {
+	if vi.Name == "" {
+		m.add(path, VIOLATION_SCHEMA, "", nil)
+		return
+	}
+
+	self := vi.Name == "__root__" || vi.Name == "__array__"
+
+	var vtype string
+	var value *DJSON
+	itemPath := path
+
+	if self {
+		vtype = tjson.GetType()
+		value = tjson
+	} else {
+		vtype = tjson.GetType(vi.Name)
+		value, _ = tjson.Get(vi.Name)
+		itemPath = appendPath(path, vi.Name)
+	}
+
+	if vtype == "" {
+		if vi.IsRequred && (vi.Type != V_TYPE_NULL || vi.hasRules()) {
+			m.add(itemPath, VIOLATION_REQUIRED, vi.TypeName, nil)
+		}
+		return
+	}
+
+	actual := value.GetAsInterface()
+
+	// the allowed values and the rules are checked when the type passes
+	if len(vi.Enum) > 0 || vi.hasRules() {
+		defer m.rules(vi, value, itemPath, len(m.violations))
+	}
+
+	switch vi.Type {
+	case V_TYPE_INT:
+		if vtype != "int" {
+			m.add(itemPath, VIOLATION_TYPE, vi.TypeName, actual)
+			return
+		}
+
+		if value.Int < vi.Min {
+			m.add(itemPath, VIOLATION_MIN, strconv.FormatInt(vi.Min, 10), actual)
+		} else if value.Int > vi.Max {
+			m.add(itemPath, VIOLATION_MAX, strconv.FormatInt(vi.Max, 10), actual)
+		}
+
+	case V_TYPE_NUMBER, V_TYPE_FLOAT: // NUMBER takes integers too
+		if vtype != "float" && (vi.Type == V_TYPE_FLOAT || vtype != "int") {
+			m.add(itemPath, VIOLATION_TYPE, vi.TypeName, actual)
+			return
+		}
+
+		sf := value.GetAsFloat()
+		if sf < vi.MinFloat {
+			m.add(itemPath, VIOLATION_MIN, strconv.FormatFloat(vi.MinFloat, 'g', -1, 64), actual)
+		} else if sf > vi.MaxFloat {
+			m.add(itemPath, VIOLATION_MAX, strconv.FormatFloat(vi.MaxFloat, 'g', -1, 64), actual)
+		}
+
+	case V_TYPE_STRING:
+		if vtype != "string" {
+			m.add(itemPath, VIOLATION_TYPE, vi.TypeName, actual)
+			return
+		}
+
+		ss := value.String
+		lenv := int64(len(ss))
+
+		if lenv < vi.Min {
+			m.add(itemPath, VIOLATION_MIN, strconv.FormatInt(vi.Min, 10), actual)
+		} else if lenv > vi.Max {
+			m.add(itemPath, VIOLATION_MAX, strconv.FormatInt(vi.Max, 10), actual)
+		}
+
+		if m.done() {
+			return
+		}
+
+		if vi.RegExp != nil {
+			if !vi.RegExp.MatchString(ss) {
+				m.add(itemPath, VIOLATION_REGEXP, vi.RegExp.String(), actual)
+			}
+		} else if vi.CheckFunc != nil && !vi.CheckFunc(ss, vi.Min, vi.Max) {
+			m.add(itemPath, VIOLATION_FORMAT, vi.TypeName, actual)
+		}
+
+	case V_TYPE_OBJECT:
+		var so *DJSON
+		var ok bool
+
+		if self {
+			so, ok = tjson.GetAsObject()
+		} else {
+			so, ok = tjson.GetAsObject(vi.Name)
+		}
+
+		if !ok {
+			if self || vi.IsRequred {
+				m.add(itemPath, VIOLATION_TYPE, vi.TypeName, actual)
+			}
+			return
+		}
+
+		for _, svi := range vi.SubItems {
+			if m.item(svi, so, itemPath); m.done() {
+				return
+			}
+		}
+
+		m.objectRules(vi, so, itemPath)
+
+	case V_TYPE_ARRAY:
+		var sa *DJSON
+		var ok bool
+
+		if self {
+			sa, ok = tjson.GetAsArray()
+		} else {
+			sa, ok = tjson.GetAsArray(vi.Name)
+		}
+
+		if !ok {
+			if self || vi.IsRequred {
+				m.add(itemPath, VIOLATION_TYPE, vi.TypeName, actual)
+			}
+			return
+		}
+
+		lenv := int64(sa.Length())
+		if lenv < vi.Min {
+			m.add(itemPath, VIOLATION_MIN, strconv.FormatInt(vi.Min, 10), actual)
+		} else if lenv > vi.Max {
+			m.add(itemPath, VIOLATION_MAX, strconv.FormatInt(vi.Max, 10), actual)
+		}
+
+		if m.done() {
+			return
+		}
+
+		for idx := 0; idx < sa.Length(); idx++ {
+			ssa, _ := sa.Get(idx)
+			elemPath := appendPath(itemPath, idx)
+			if m.alternatives(vi.itemsAt(idx), ssa, elemPath, elemPath, ssa); m.done() {
+				return
+			}
+		}
+
+		m.arrayRules(vi, sa, itemPath)
+
+	case V_TYPE_BOOL:
+		if vtype != "bool" && vi.IsRequred {
+			m.add(itemPath, VIOLATION_TYPE, vi.TypeName, actual)
+		}
+
+	case V_TYPE_NIL:
+		if vtype != "null" {
+			m.add(itemPath, VIOLATION_TYPE, vi.TypeName, actual)
+		}
+
+	case V_TYPE_MULTI:
+		m.alternatives(vi.SubItems, tjson, path, itemPath, value)
+
+	case V_TYPE_REF:
+		if vi.Ref == nil {
+			m.add(itemPath, VIOLATION_SCHEMA, vi.RefName, actual)
+			return
+		}
+		m.item(vi.Ref, value, itemPath) // the value itself, as Ref is __root__
+	}
+}
+
+// alternatives passes if one of items passes. Otherwise it reports the
+// violations of the only one whose type matches the value, or
+// VIOLATION_NO_MATCH.
+
+func (m *vcheck) alternatives(items []*VItem, tjson *DJSON, path, itemPath []interface{}, value *DJSON) {
+	if len(items) == 0 {
+		return
+	}
+
+	results := make([]Violations, len(items))
+	for idx, vi := range items {
+		c := &vcheck{all: m.all}
+		if c.item(vi, tjson, path); len(c.violations) == 0 {
+			return
+		}
+		results[idx] = c.violations
+	}
+
+	if len(items) == 1 {
+		m.violations = append(m.violations, results[0]...)
+		return
+	}
+
+	matched := -1
+	names := make([]string, len(items))
+	for idx, vi := range items {
+		names[idx] = vi.TypeName
+		if value != nil && sameBaseType(vi, value.GetType()) {
+			if matched >= 0 {
+				matched = len(items) // more than one
+			} else {
+				matched = idx
+			}
+		}
+	}
+
+	if matched >= 0 && matched < len(items) {
+		m.violations = append(m.violations, results[matched]...)
+		return
+	}
+
+	var actual interface{}
+	if value != nil {
+		actual = value.GetAsInterface()
+	}
+	m.add(itemPath, VIOLATION_NO_MATCH, strings.Join(names, "|"), actual)
+}
+
+func sameBaseType(vi *VItem, vtype string) bool {
+	switch vi.Type {
+	case V_TYPE_REF:
+		return vi.Ref != nil && sameBaseType(vi.Ref, vtype)
+	case V_TYPE_INT:
+		return vtype == "int"
+	case V_TYPE_FLOAT:
+		return vtype == "float"
+	case V_TYPE_NUMBER:
+		return vtype == "int" || vtype == "float"
+	case V_TYPE_STRING:
+		return vtype == "string"
+	case V_TYPE_BOOL:
+		return vtype == "bool"
+	case V_TYPE_NIL:
+		return vtype == "null"
+	case V_TYPE_OBJECT:
+		return vtype == "object"
+	case V_TYPE_ARRAY:
+		return vtype == "array"
+	}
+	return false
+}
+
+// enumMatch compares numbers by value, so 1 matches 1.0.
+
+func enumMatch(vi *VItem, value *DJSON) bool {
+	for _, e := range vi.Enum {
+		switch t := e.(type) {
+		case string:
+			if value.IsString() && (value.String == t || vi.IgnoreCase && strings.EqualFold(value.String, t)) {
+				return true
+			}
+		case int64:
+			if value.IsInt() && value.Int == t || value.IsFloat() && value.Float == float64(t) {
+				return true
+			}
+		case float64:
+			if value.IsNumeric() && value.GetAsFloat() == t {
+				return true
+			}
+		case bool:
+			if value.IsBool() && value.Bool == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
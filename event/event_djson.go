@@ -0,0 +1,27 @@
+package event
+
+import "github.com/lokks307/go-util/djson"
+
+// WatchDJSON publishes every change of dj at or around path to the Bus as an
+// AEvent of type etype. DataStrs holds the changed path, DataJson the new
+// value (nil when removed) and Data the djson.Change itself, with Old and New
+// cloned since handlers run while dj may change.
+// Returns the watch id to be passed to dj.Unwatch.
+
+func WatchDJSON(dj *djson.DJSON, path string, etype string) int {
+	return dj.Watch(path, func(c djson.Change) {
+		if c.Old != nil {
+			c.Old = c.Old.Clone()
+		}
+		if c.New != nil {
+			c.New = c.New.Clone()
+		}
+
+		Bus <- AEvent{
+			Type:     etype,
+			Data:     c,
+			DataStrs: []string{c.Path},
+			DataJson: c.New,
+		}
+	})
+}
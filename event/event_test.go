@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/lokks307/go-util/djson"
 )
 
 func TestEventBus(t *testing.T) {
@@ -36,3 +38,57 @@ func TestEventBus(t *testing.T) {
 
 	time.Sleep(5 * time.Second)
 }
+
+func TestWatchDJSON(t *testing.T) {
+
+	done := make(chan AEvent, 1)
+
+	On("configChanged", func(ae AEvent) {
+		done <- ae
+	})
+
+	_ = Manager.Run()
+
+	config := djson.NewDJSON().Parse(`{"server":{"port":8080}}`)
+	WatchDJSON(config, `["server"]["port"]`, "configChanged")
+
+	_ = config.UpdatePath(`["server"]["port"]`, 9090)
+
+	select {
+	case ae := <-done:
+		if ae.DataStrs[0] != `["server"]["port"]` || ae.DataJson.GetAsInt() != 9090 {
+			t.Fatal("unexpected event", ae.DataStrs, ae.DataJson.ToString())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event published")
+	}
+}
+
+func TestWatchDJSONClone(t *testing.T) {
+
+	done := make(chan AEvent, 1)
+
+	On("serverChanged", func(ae AEvent) {
+		time.Sleep(100 * time.Millisecond) // after the document changed again
+		done <- ae
+	})
+
+	_ = Manager.Run()
+
+	config := djson.NewDJSON().Parse(`{"server":{"port":8080}}`)
+	id := WatchDJSON(config, `["server"]`, "serverChanged")
+
+	config.Put("server", djson.Object{"port": 9090})
+	config.Unwatch(id)
+	_ = config.UpdatePath(`["server"]["port"]`, 1)
+
+	select {
+	case ae := <-done:
+		c := ae.Data.(djson.Change)
+		if ae.DataJson.GetAsInt("port") != 9090 || c.New.GetAsInt("port") != 9090 || c.Old.GetAsInt("port") != 8080 {
+			t.Fatal("payload changed with the document", ae.DataJson.ToString())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event published")
+	}
+}